@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listGroupActivityHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.CursorFilters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	input.CursorFilters.Cursor = int64(app.readInt(qs, "cursor", 0, v))
+	input.CursorFilters.PageSize = app.readInt(qs, "page_size", 20, v)
+	since := app.readTime(qs, "since", v)
+
+	if data.ValidateCursorFilters(v, input.CursorFilters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	activities, metadata, err := app.models.Activities.GetAllForGroup(groupID, since, input.CursorFilters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"activities": activities, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// recordActivity adds an entry to a group's activity feed. It is called once
+// the change itself has been saved, so a failure is logged rather than turned
+// into an error response.
+func (app *application) recordActivity(activity *data.Activity) {
+	err := app.models.Activities.Insert(activity)
+	if err != nil {
+		app.logger.Error(err.Error(), "group_id", activity.GroupID, "action", activity.Action)
+	}
+}
+
+func expenseActivity(action string, actorID *int64, expense *data.Expense) *data.Activity {
+	return &data.Activity{
+		GroupID:   expense.GroupID,
+		ActorID:   actorID,
+		Action:    action,
+		ExpenseID: &expense.ID,
+		Details: map[string]any{
+			"description": expense.Description,
+			"amount":      expense.Amount,
+			"currency":    expense.Currency,
+		},
+	}
+}
+
+func settlementActivity(action string, actorID *int64, settlement *data.Settlement) *data.Activity {
+	return &data.Activity{
+		GroupID:      settlement.GroupID,
+		ActorID:      actorID,
+		Action:       action,
+		SettlementID: &settlement.ID,
+		Details: map[string]any{
+			"payer_id": settlement.PayerID,
+			"payee_id": settlement.PayeeID,
+			"amount":   settlement.Amount,
+			"currency": settlement.Currency,
+		},
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/storage"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// multipartOverhead is how much larger than the file itself an upload request
+// body may be, to leave room for part headers and boundaries.
+const multipartOverhead = 64 * 1024
+
+func (app *application) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	belongsToGroup, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"])
+	if err != nil || !belongsToGroup {
+		return
+	}
+
+	attachments, err := app.models.Attachments.GetAllForExpense(ids["expense_id"])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"attachments": attachments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// uploadAttachmentHandler expects a multipart/form-data body with the receipt
+// in a part named "file". The file is streamed to storage rather than being
+// buffered in memory.
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	belongsToGroup, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"])
+	if err != nil || !belongsToGroup {
+		return
+	}
+
+	maxSize := app.config.storage.maxUploadSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	part, err := reader.NextPart()
+	for err == nil && part.FormName() != "file" {
+		part, err = reader.NextPart()
+	}
+
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		v.AddError("file", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	case errors.As(err, &maxBytesError):
+		app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+		return
+	case err != nil:
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer part.Close()
+
+	head := make([]byte, 512)
+
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	head = head[:n]
+
+	key, err := data.NewAttachmentKey(ids["expense_id"])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attachment := &data.Attachment{
+		ExpenseID:   ids["expense_id"],
+		UploadedBy:  &currentUser.ID,
+		Filename:    part.FileName(),
+		ContentType: http.DetectContentType(head),
+		StorageKey:  key,
+	}
+
+	// Reading one byte past the limit is enough to tell that the file is too
+	// large without storing all of it.
+	body := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head), part), maxSize+1)}
+
+	err = app.storage.Put(r.Context(), attachment.StorageKey, body)
+	if err != nil {
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	attachment.Size = body.n
+
+	if data.ValidateAttachment(v, attachment, maxSize); !v.Valid() {
+		app.removeStoredFile(attachment.StorageKey)
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Attachments.Insert(attachment)
+	if err != nil {
+		app.removeStoredFile(attachment.StorageKey)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/groups/%d/expenses/%d/attachments/%d", ids["group_id"], ids["expense_id"], attachment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id", "attachment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	belongsToGroup, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"])
+	if err != nil || !belongsToGroup {
+		return
+	}
+
+	attachment, err := app.models.Attachments.Get(ids["expense_id"], ids["attachment_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	file, err := app.storage.Open(r.Context(), attachment.StorageKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// The status has already been sent by the time copying fails, so all that
+	// can be done is to log it.
+	_, err = io.Copy(w, file)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id", "attachment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	belongsToGroup, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"])
+	if err != nil || !belongsToGroup {
+		return
+	}
+
+	attachment, err := app.models.Attachments.Get(ids["expense_id"], ids["attachment_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Attachments.Delete(ids["expense_id"], attachment.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.removeStoredFile(attachment.StorageKey)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "attachment successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeStoredFile deletes a file that no row refers to any more. A failure
+// only leaves an unreachable file behind, so it is logged and not reported.
+func (app *application) removeStoredFile(key string) {
+	err := app.storage.Delete(context.Background(), key)
+	if err != nil {
+		app.logger.Error(err.Error(), "storage_key", key)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AuditFilters
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.AuditFilters.ActorID = int64(app.readInt(qs, "actor_id", 0, v))
+	input.AuditFilters.Entity = app.readString(qs, "entity", "")
+	input.AuditFilters.EntityID = int64(app.readInt(qs, "entity_id", 0, v))
+	input.AuditFilters.GroupID = int64(app.readInt(qs, "group_id", 0, v))
+	input.AuditFilters.From = app.readDate(qs, "from", v)
+	input.AuditFilters.To = app.readDate(qs, "to", v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-id")
+	input.Filters.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+
+	if input.AuditFilters.Entity != "" {
+		v.Check(validator.PermittedValue(input.AuditFilters.Entity, data.AuditEntityExpense, data.AuditEntityGroup, data.AuditEntityMember, data.AuditEntityParticipant, data.AuditEntitySettlement), "entity", "invalid entity value")
+	}
+
+	app.validateDateRange(v, input.AuditFilters.From, input.AuditFilters.To)
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := app.models.Audit.GetAll(input.AuditFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit_log": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
 )
 
 func (app *application) groupBalanceHandler(w http.ResponseWriter, r *http.Request) {
@@ -11,11 +14,44 @@ func (app *application) groupBalanceHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	balances, err := app.models.Balances.CalculateGroupBalances(groupID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		app.forbiddenResponse(w, r)
+	err = app.writeJSON(w, http.StatusOK, envelope{"currency": group.Currency, "balances": balances}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) groupSettleUpHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -25,7 +61,111 @@ func (app *application) groupBalanceHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"balances": balances}, nil)
+	transfers := data.SimplifyDebts(balances)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"currency": group.Currency, "transfers": transfers}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) recordSettleUpHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	settlements, err := app.models.Settlements.SettleUp(groupID, &currentUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, settlement := range settlements {
+		app.recordActivity(settlementActivity(data.ActivitySettlementCreated, &currentUser.ID, settlement))
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"settlements": settlements}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) groupDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	debts, err := app.models.Balances.CalculatePairwiseDebts(groupID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"currency": group.Currency, "debts": debts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) userBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	group, err := app.models.Groups.Get(ids["group_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	debts, err := app.models.Balances.CalculatePairwiseDebts(ids["group_id"])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	counterparties := data.CounterpartiesFor(ids["user_id"], debts)
+
+	var balance data.Money
+	for _, counterparty := range counterparties {
+		balance += counterparty.Balance
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"currency":       group.Currency,
+		"user_id":        ids["user_id"],
+		"balance":        balance,
+		"counterparties": counterparties,
+	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
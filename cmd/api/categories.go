@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	categories, err := app.models.Categories.GetAllForGroup(groupID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "category_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(ids["group_id"], ids["category_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &data.Category{
+		GroupID: &groupID,
+		Name:    input.Name,
+	}
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Insert(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEntry):
+			v.AddError("name", "a category with this name already exists in the group")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/groups/%d/categories/%d", groupID, category.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "category_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(ids["group_id"], ids["category_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if category.BuiltIn {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Update(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEntry):
+			v.AddError("name", "a category with this name already exists in the group")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "category_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Categories.Delete(ids["group_id"], ids["category_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateExpenseCategory checks that the category, if any, is available to
+// the group. Problems are recorded in v rather than returned as an error.
+func (app *application) validateExpenseCategory(v *validator.Validator, groupID int64, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	_, err := app.models.Categories.Get(groupID, *categoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category_id", "must be a built-in category or one of the group's own")
+			return nil
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
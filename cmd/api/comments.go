@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// The comment handlers serve both /expenses/:expense_id/comments and
+// /settlements/:settlement_id/comments, so they work out which of the two the
+// comment belongs to from the route parameters.
+
+type commentTarget struct {
+	expenseID    *int64
+	settlementID *int64
+}
+
+func (t commentTarget) owns(comment *data.Comment) bool {
+	switch {
+	case t.expenseID != nil:
+		return comment.ExpenseID != nil && *comment.ExpenseID == *t.expenseID
+	default:
+		return comment.SettlementID != nil && *comment.SettlementID == *t.settlementID
+	}
+}
+
+// readCommentTarget checks that the expense or settlement is part of the group.
+// If not, it sends the response itself and returns false.
+func (app *application) readCommentTarget(w http.ResponseWriter, r *http.Request) (commentTarget, bool) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return commentTarget{}, false
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+
+	if params.ByName("expense_id") != "" {
+		expenseID, err := app.readIDParam(r, "expense_id")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return commentTarget{}, false
+		}
+
+		belongsToGroup, err := app.checkExpenseInGroup(w, r, expenseID, groupID)
+		if err != nil || !belongsToGroup {
+			return commentTarget{}, false
+		}
+
+		return commentTarget{expenseID: &expenseID}, true
+	}
+
+	settlementID, err := app.readIDParam(r, "settlement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return commentTarget{}, false
+	}
+
+	_, err = app.models.Settlements.Get(settlementID, groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return commentTarget{}, false
+	}
+
+	return commentTarget{settlementID: &settlementID}, true
+}
+
+func (app *application) listCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := app.readCommentTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "updated_at", "-id", "-created_at", "-updated_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var comments []*data.Comment
+	var metadata data.Metadata
+	var err error
+
+	if target.expenseID != nil {
+		comments, metadata, err = app.models.Comments.GetAllForExpense(*target.expenseID, input.Filters)
+	} else {
+		comments, metadata, err = app.models.Comments.GetAllForSettlement(*target.settlementID, input.Filters)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"comments": comments, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := app.readCommentTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	comment := &data.Comment{
+		ExpenseID:    target.expenseID,
+		SettlementID: target.settlementID,
+		AuthorID:     &currentUser.ID,
+		Body:         input.Body,
+	}
+
+	v := validator.New()
+
+	if data.ValidateComment(v, comment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Comments.Insert(comment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, comment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"comment": comment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnComment fetches the comment being edited or deleted and checks that
+// it belongs to the target and was written by the current user.
+func (app *application) readOwnComment(w http.ResponseWriter, r *http.Request, target commentTarget) (*data.Comment, bool) {
+	commentID, err := app.readIDParam(r, "comment_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	comment, err := app.models.Comments.Get(commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !target.owns(comment) {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	if comment.AuthorID == nil || *comment.AuthorID != currentUser.ID {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	return comment, true
+}
+
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := app.readCommentTarget(w, r)
+	if !ok {
+		return
+	}
+
+	comment, ok := app.readOwnComment(w, r, target)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body *string `json:"body"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Body != nil {
+		comment.Body = *input.Body
+	}
+
+	v := validator.New()
+
+	if data.ValidateComment(v, comment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Comments.Update(comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"comment": comment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := app.readCommentTarget(w, r)
+	if !ok {
+		return
+	}
+
+	comment, ok := app.readOwnComment(w, r, target)
+	if !ok {
+		return
+	}
+
+	err := app.models.Comments.Delete(comment.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "comment successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -9,7 +9,11 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey        = contextKey("user")
+	groupMemberContextKey = contextKey("groupMember")
+	sessionIDContextKey   = contextKey("sessionID")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -24,3 +28,31 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func (app *application) contextSetGroupMember(r *http.Request, member *data.GroupMember) *http.Request {
+	ctx := context.WithValue(r.Context(), groupMemberContextKey, member)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetGroupMember(r *http.Request) *data.GroupMember {
+	member, ok := r.Context().Value(groupMemberContextKey).(*data.GroupMember)
+	if !ok {
+		panic("missing group member value in request context")
+	}
+
+	return member
+}
+
+func (app *application) contextSetSessionID(r *http.Request, sessionID int64) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionIDContextKey, sessionID)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetSessionID(r *http.Request) int64 {
+	sessionID, ok := r.Context().Value(sessionIDContextKey).(int64)
+	if !ok {
+		panic("missing session ID value in request context")
+	}
+
+	return sessionID
+}
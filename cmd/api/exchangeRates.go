@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	exchangeRates, err := app.models.ExchangeRates.GetAllForGroup(groupID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"exchange_rates": exchangeRates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) putExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Rate data.Rate `json:"rate"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	exchangeRate := &data.ExchangeRate{
+		GroupID:  groupID,
+		Currency: app.readCurrencyParam(r),
+		Rate:     input.Rate,
+	}
+
+	v := validator.New()
+
+	if data.ValidateExchangeRate(v, exchangeRate, group.Currency); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ExchangeRates.Upsert(exchangeRate)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"exchange_rate": exchangeRate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.ExchangeRates.Delete(groupID, app.readCurrencyParam(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "exchange rate successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readCurrencyParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	return strings.ToUpper(params.ByName("currency"))
+}
+
+// lookupExchangeRate validates the currency and looks up the rate used to
+// convert amounts in it into the group's base currency. Any problem with the
+// currency is recorded in v rather than returned as an error.
+func (app *application) lookupExchangeRate(v *validator.Validator, group *data.Group, currency string) (data.Rate, error) {
+	data.ValidateCurrency(v, "currency", currency)
+	if _, invalid := v.Errors["currency"]; invalid {
+		return 0, nil
+	}
+
+	if currency == group.Currency {
+		return data.RateOne, nil
+	}
+
+	exchangeRate, err := app.models.ExchangeRates.Get(group.ID, currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("currency", "no exchange rate to "+group.Currency+" has been defined for this currency")
+			return 0, nil
+		default:
+			return 0, err
+		}
+	}
+
+	return exchangeRate.Rate, nil
+}
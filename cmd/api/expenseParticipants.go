@@ -1,9 +1,7 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/manuelam2003/triclone/internal/data"
@@ -17,13 +15,6 @@ func (app *application) listExpenseParticipantsHandler(w http.ResponseWriter, r
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, ids["group_id"])
-	if err != nil || !isMember {
-		return
-	}
-
 	if _, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"]); err != nil {
 		return
 	}
@@ -55,8 +46,8 @@ func (app *application) listExpenseParticipantsHandler(w http.ResponseWriter, r
 }
 
 type Participant struct {
-	UserID     int64   `json:"user_id"`
-	AmountOwed float64 `json:"amount_owed"`
+	UserID     int64      `json:"user_id"`
+	AmountOwed data.Money `json:"amount_owed"`
 }
 
 func (app *application) addExpenseParticipantsHandler(w http.ResponseWriter, r *http.Request) {
@@ -68,37 +59,36 @@ func (app *application) addExpenseParticipantsHandler(w http.ResponseWriter, r *
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, ids["group_id"])
-	if err != nil || !isMember {
-		return
-	}
-
-	if _, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"]); err != nil {
+	expense, err := app.models.Expenses.Get(ids["group_id"], ids["expense_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	var participants []Participant
 
-	if err := json.NewDecoder(r.Body).Decode(&participants); err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
-	err = app.validateExpenseParticipants(ids["group_id"], ids["expense_id"], participants)
+	err = app.readJSON(w, r, &participants)
 	if err != nil {
-		app.failedValidationResponse(w, r, map[string]string{"amount": err.Error()})
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	newRecords := 0
+	var newParticipants []*data.ExpenseParticipant
 	var invalidParticipants []int64
 	var membershipErrors []int64
-	v := validator.New()
 
 	for _, participant := range participants {
-
-		isMember, err := app.checkUserMembership(w, r, participant.UserID, ids["group_id"])
-		if err != nil || !isMember {
+		isMember, err := app.models.GroupMembers.UserBelongsToGroup(participant.UserID, ids["group_id"])
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !isMember {
 			membershipErrors = append(membershipErrors, participant.UserID)
 			continue
 		}
@@ -109,27 +99,46 @@ func (app *application) addExpenseParticipantsHandler(w http.ResponseWriter, r *
 			AmountOwed: participant.AmountOwed,
 		}
 
-		if data.ValidateParticipant(v, newParticipant); !v.Valid() {
+		pv := validator.New()
+
+		if data.ValidateParticipant(pv, newParticipant); !pv.Valid() {
 			invalidParticipants = append(invalidParticipants, participant.UserID)
 			continue
 		}
 
-		err = app.models.ExpensesParticipants.Insert(newParticipant)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrDuplicateEntry):
-				v.AddError("unique", "a expense with this ID and user_id already exists")
-				app.failedValidationResponse(w, r, v.Errors)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
+		newParticipants = append(newParticipants, newParticipant)
+	}
+
+	v := validator.New()
+
+	err = app.models.ExpensesParticipants.InsertAll(expense.ID, newParticipants, &currentUser.ID)
+	if err != nil {
+		var shareErr *data.ShareError
+		switch {
+		case errors.As(err, &shareErr):
+			v.AddError("amount", shareErr.Message)
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateEntry):
+			v.AddError("unique", "a expense with this ID and user_id already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
 		}
-		newRecords++
+		return
 	}
 
+	app.recordActivity(&data.Activity{
+		GroupID:   expense.GroupID,
+		ActorID:   &currentUser.ID,
+		Action:    data.ActivityParticipantsAdded,
+		ExpenseID: &expense.ID,
+		Details:   map[string]any{"count": len(newParticipants)},
+	})
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"message": "expense participants added succesfully",
-		"newRecords":          newRecords,
+		"newRecords":          len(newParticipants),
 		"invalidParticipants": invalidParticipants,
 		"membershipErrors":    membershipErrors,
 	}, nil)
@@ -147,12 +156,14 @@ func (app *application) updateExpenseParticipantHandler(w http.ResponseWriter, r
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, ids["group_id"])
-	if err != nil || !isMember {
-		return
-	}
-
-	if _, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"]); err != nil {
+	expense, err := app.models.Expenses.Get(ids["group_id"], ids["expense_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -172,13 +183,13 @@ func (app *application) updateExpenseParticipantHandler(w http.ResponseWriter, r
 		return
 	}
 
-	isMember, err = app.checkUserMembership(w, r, participant.UserID, ids["group_id"])
+	isMember, err := app.checkUserMembership(w, r, participant.UserID, ids["group_id"])
 	if err != nil || !isMember {
 		return
 	}
 
 	var input struct {
-		AmountOwed float64 `json:"amount_owed"`
+		AmountOwed data.Money `json:"amount_owed"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -189,12 +200,39 @@ func (app *application) updateExpenseParticipantHandler(w http.ResponseWriter, r
 
 	participant.AmountOwed = input.AmountOwed
 
-	err = app.models.ExpensesParticipants.Update(participant)
+	v := validator.New()
+
+	if data.ValidateParticipant(v, participant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ExpensesParticipants.Update(participant, &currentUser.ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		var shareErr *data.ShareError
+		switch {
+		case errors.As(err, &shareErr):
+			v.AddError("amount", shareErr.Message)
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	app.recordActivity(&data.Activity{
+		GroupID:   expense.GroupID,
+		ActorID:   &currentUser.ID,
+		Action:    data.ActivityParticipantUpdated,
+		ExpenseID: &expense.ID,
+		UserID:    &participant.UserID,
+		Details:   map[string]any{"amount_owed": participant.AmountOwed},
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"participant": participant}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -210,12 +248,14 @@ func (app *application) deleteExpenseParticipantHandler(w http.ResponseWriter, r
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, ids["group_id"])
-	if err != nil || !isMember {
-		return
-	}
-
-	if _, err := app.checkExpenseInGroup(w, r, ids["expense_id"], ids["group_id"]); err != nil {
+	expense, err := app.models.Expenses.Get(ids["group_id"], ids["expense_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -234,20 +274,35 @@ func (app *application) deleteExpenseParticipantHandler(w http.ResponseWriter, r
 		return
 	}
 
-	isMember, err = app.checkUserMembership(w, r, participant.UserID, ids["group_id"])
+	isMember, err := app.checkUserMembership(w, r, participant.UserID, ids["group_id"])
 	if err != nil || !isMember {
 		return
 	}
 
-	err = app.models.ExpensesParticipants.Delete(ids["participant_id"])
+	err = app.models.ExpensesParticipants.Delete(expense.ID, participant.ID, &currentUser.ID)
 	if err != nil {
-		if errors.Is(err, data.ErrRecordNotFound) {
+		var shareErr *data.ShareError
+		switch {
+		case errors.As(err, &shareErr):
+			v := validator.New()
+			v.AddError("amount", shareErr.Message)
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
-		} else {
+		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
+
+	app.recordActivity(&data.Activity{
+		GroupID:   expense.GroupID,
+		ActorID:   &currentUser.ID,
+		Action:    data.ActivityParticipantRemoved,
+		ExpenseID: &expense.ID,
+		UserID:    &participant.UserID,
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "participant successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -266,21 +321,3 @@ func (app *application) checkExpenseInGroup(w http.ResponseWriter, r *http.Reque
 	}
 	return true, nil
 }
-
-func (app *application) validateExpenseParticipants(groupID, expenseID int64, participants []Participant) error {
-	expense, err := app.models.Expenses.Get(groupID, expenseID)
-	if err != nil {
-		return err
-	}
-
-	totalOwed := 0.0
-	for _, participant := range participants {
-		totalOwed += participant.AmountOwed
-	}
-
-	if totalOwed > expense.Amount {
-		return fmt.Errorf("total participants' amount owed exceeds the expense amount")
-	}
-
-	return nil
-}
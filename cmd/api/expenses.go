@@ -19,34 +19,35 @@ func (app *application) listGroupExpensesHandler(w http.ResponseWriter, r *http.
 	var input struct {
 		Description string
 		PaidBy      int64
+		CategoryID  int64
+		From        *data.Date
+		To          *data.Date
 		data.Filters
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	v := validator.New()
 
 	qs := r.URL.Query()
 
 	input.PaidBy = int64(app.readInt(qs, "paid_by", 0, v))
+	input.CategoryID = int64(app.readInt(qs, "category_id", 0, v))
+	input.From = app.readDate(qs, "from", v)
+	input.To = app.readDate(qs, "to", v)
 	input.Description = app.readString(qs, "description", "")
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "amount", "description", "paid_by", "updated_at", "-id", "-amount", "-description", "-paid_by", "-updated_at"}
+	input.Filters.SortSafelist = []string{"id", "amount", "description", "paid_by", "category_id", "incurred_on", "updated_at", "-id", "-amount", "-description", "-paid_by", "-category_id", "-incurred_on", "-updated_at"}
+
+	app.validateDateRange(v, input.From, input.To)
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	expenses, metadata, err := app.models.Expenses.GetAll(groupID, input.Description, input.PaidBy, input.Filters)
+	expenses, metadata, err := app.models.Expenses.GetAll(groupID, input.Description, input.PaidBy, input.CategoryID, input.From, input.To, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -71,13 +72,6 @@ func (app *application) showGroupExpenseHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	expense, err := app.models.Expenses.Get(groupID, expenseID)
 	if err != nil {
 		switch {
@@ -104,8 +98,13 @@ func (app *application) createGroupExpenseHandler(w http.ResponseWriter, r *http
 
 	// TODO only current user can make an expense
 	var input struct {
-		Amount      float64 `json:"amount"`
-		Description string  `json:"description"`
+		Amount      data.Money  `json:"amount"`
+		Currency    string      `json:"currency"`
+		Description string      `json:"description"`
+		CategoryID  *int64      `json:"category_id"`
+		IncurredOn  *data.Date  `json:"incurred_on"`
+		Split       *data.Split `json:"split"`
+		Incomplete  *bool       `json:"incomplete"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -116,35 +115,108 @@ func (app *application) createGroupExpenseHandler(w http.ResponseWriter, r *http
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	expense := &data.Expense{
-		GroupID:     groupID,
-		Amount:      input.Amount,
-		Description: input.Description,
-		PaidBy:      &currentUser.ID,
+	if input.Currency == "" {
+		input.Currency = group.Currency
 	}
 
 	v := validator.New()
 
+	rate, err := app.lookupExchangeRate(v, group, input.Currency)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if input.IncurredOn == nil {
+		today := data.Today()
+		input.IncurredOn = &today
+	}
+
+	// Expenses created without a split have no participants yet, so unless the
+	// client says otherwise they start out flagged as incomplete.
+	if input.Incomplete == nil {
+		incomplete := input.Split == nil
+		input.Incomplete = &incomplete
+	}
+
+	expense := &data.Expense{
+		GroupID:      groupID,
+		Amount:       input.Amount,
+		Currency:     input.Currency,
+		ExchangeRate: rate,
+		BaseAmount:   input.Amount.Convert(rate),
+		Description:  input.Description,
+		PaidBy:       &currentUser.ID,
+		CategoryID:   input.CategoryID,
+		IncurredOn:   *input.IncurredOn,
+		Incomplete:   *input.Incomplete,
+	}
+
+	err = app.validateExpenseCategory(v, groupID, expense.CategoryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	if data.ValidateExpense(v, expense); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Expenses.Insert(expense)
+	env := envelope{"expense": expense}
+
+	var participants []*data.ExpenseParticipant
+
+	if input.Split != nil {
+		if data.ValidateSplit(v, input.Split, expense.Amount); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		participants = input.Split.Allocate(expense.Amount)
+
+		err = app.validateSplitMembers(v, groupID, input.Split)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["participants"] = participants
+	}
+
+	var shares data.Money
+	for _, participant := range participants {
+		shares += participant.AmountOwed
+	}
+
+	if data.ValidateExpenseShares(v, expense, shares); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Expenses.InsertWithParticipants(expense, participants, &currentUser.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.recordActivity(expenseActivity(data.ActivityExpenseCreated, &currentUser.ID, expense))
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/groups/%d/expenses/%d", groupID, expense.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"expense": expense}, headers)
+	err = app.writeJSON(w, http.StatusCreated, env, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -165,11 +237,6 @@ func (app *application) updateGroupExpenseHandler(w http.ResponseWriter, r *http
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	expense, err := app.models.Expenses.Get(groupID, expenseID)
 	if err != nil {
 
@@ -183,8 +250,11 @@ func (app *application) updateGroupExpenseHandler(w http.ResponseWriter, r *http
 	}
 
 	var input struct {
-		Amount      *float64 `json:"amount"`
-		Description *string  `json:"description"`
+		Amount      *data.Money `json:"amount"`
+		Description *string     `json:"description"`
+		CategoryID  *int64      `json:"category_id"`
+		IncurredOn  *data.Date  `json:"incurred_on"`
+		Incomplete  *bool       `json:"incomplete"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -195,22 +265,51 @@ func (app *application) updateGroupExpenseHandler(w http.ResponseWriter, r *http
 
 	if input.Amount != nil {
 		expense.Amount = *input.Amount
+		expense.BaseAmount = expense.Amount.Convert(expense.ExchangeRate)
 	}
 
 	if input.Description != nil {
 		expense.Description = *input.Description
 	}
 
+	// A category_id of 0 removes the expense's category.
+	if input.CategoryID != nil {
+		expense.CategoryID = input.CategoryID
+		if *input.CategoryID == 0 {
+			expense.CategoryID = nil
+		}
+	}
+
+	if input.IncurredOn != nil {
+		expense.IncurredOn = *input.IncurredOn
+	}
+
+	if input.Incomplete != nil {
+		expense.Incomplete = *input.Incomplete
+	}
+
 	v := validator.New()
 
-	if data.ValidateExpense(v, expense); !v.Valid() {
+	data.ValidateExpense(v, expense)
+
+	err = app.validateExpenseCategory(v, groupID, expense.CategoryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Expenses.Update(expense)
+	err = app.models.Expenses.Update(expense, &currentUser.ID)
 	if err != nil {
+		var shareErr *data.ShareError
 		switch {
+		case errors.As(err, &shareErr):
+			v.AddError("amount", shareErr.Message)
+			app.failedValidationResponse(w, r, v.Errors)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -219,6 +318,8 @@ func (app *application) updateGroupExpenseHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	app.recordActivity(expenseActivity(data.ActivityExpenseUpdated, &currentUser.ID, expense))
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"expense": expense}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -241,12 +342,7 @@ func (app *application) deleteGroupExpenseHandler(w http.ResponseWriter, r *http
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
-	err = app.models.Expenses.Delete(groupID, expenseID)
+	err = app.models.Expenses.SoftDelete(groupID, expenseID, &currentUser.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -257,8 +353,29 @@ func (app *application) deleteGroupExpenseHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "expense successfully deleted"}, nil)
+	app.recordActivity(&data.Activity{
+		GroupID:   groupID,
+		ActorID:   &currentUser.ID,
+		Action:    data.ActivityExpenseDeleted,
+		ExpenseID: &expenseID,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "expense successfully moved to trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// validateSplitMembers checks that everyone in the split belongs to the group.
+// Problems are recorded in v rather than returned as an error.
+func (app *application) validateSplitMembers(v *validator.Validator, groupID int64, split *data.Split) error {
+	for _, participant := range split.Participants {
+		isMember, err := app.models.GroupMembers.UserBelongsToGroup(participant.UserID, groupID)
+		if err != nil {
+			return err
+		}
+		v.Check(isMember, "split.participants", fmt.Sprintf("user %d is not a member of this group", participant.UserID))
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listGroupInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "expiry", "-id", "-created_at", "-expiry"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	invites, metadata, err := app.models.GroupInvites.GetAllForGroup(groupID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"invites": invites, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createGroupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Email   *string    `json:"email"`
+		Expiry  *time.Time `json:"expiry"`
+		MaxUses *int       `json:"max_uses"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	invite := &data.GroupInvite{
+		GroupID:   groupID,
+		CreatedBy: &currentUser.ID,
+		Email:     input.Email,
+		Expiry:    input.Expiry,
+		MaxUses:   input.MaxUses,
+	}
+
+	v := validator.New()
+
+	if data.ValidateGroupInvite(v, invite); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.GroupInvites.Insert(invite)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if invite.Email != nil {
+		group, err := app.models.Groups.Get(groupID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.sendEmail(*invite.Email, "group_invite.tmpl", map[string]any{
+			"inviterName": currentUser.Name,
+			"groupName":   group.Name,
+			"inviteToken": invite.Token,
+			"expiry":      invite.Expiry,
+		})
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/groups/%d/invites/%d", groupID, invite.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"invite": invite}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteGroupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "invite_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.GroupInvites.Delete(ids["group_id"], ids["invite_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "invite successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) redeemGroupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	invite, err := app.models.GroupInvites.Redeem(input.TokenPlaintext, currentUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired invite token")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateEntry):
+			v.AddError("member", "this user is already a member of this group")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordActivity(inviteActivity(currentUser, invite))
+
+	group, err := app.models.Groups.Get(invite.GroupID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"group": group}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// claimGroupInvites adds a newly activated user to every group they were
+// invited to by email. The account is activated by then, so a failure is
+// logged rather than turned into an error response.
+func (app *application) claimGroupInvites(user *data.User) {
+	invites, err := app.models.GroupInvites.ClaimForUser(user)
+	if err != nil {
+		app.logger.Error(err.Error(), "user_id", user.ID)
+		return
+	}
+
+	for _, invite := range invites {
+		app.recordActivity(inviteActivity(user, invite))
+	}
+}
+
+func inviteActivity(user *data.User, invite *data.GroupInvite) *data.Activity {
+	return &data.Activity{
+		GroupID: invite.GroupID,
+		ActorID: &user.ID,
+		Action:  data.ActivityMemberJoined,
+		UserID:  &user.ID,
+		Details: map[string]any{"invite_id": invite.ID},
+	}
+}
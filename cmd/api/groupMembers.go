@@ -15,38 +15,71 @@ func (app *application) addGroupMemberHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
+	var input struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
 	v := validator.New()
+	v.Check(input.UserID > 0, "user_id", "must be a positive integer")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	err = app.models.GroupMembers.Insert(groupID, currentUser.ID)
+	_, err = app.models.Users.GetByID(input.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("user_id", "a user with this user_id does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.GroupMembers.Insert(groupID, input.UserID, &currentUser.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEntry):
 			v.AddError("member", "this user is already a member of this group")
 			app.failedValidationResponse(w, r, v.Errors)
-		case errors.Is(err, data.ErrForeignKeyViolation):
-			v.AddError("group_id", "a group with this group_id does not exist")
-			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
+	app.recordActivity(&data.Activity{
+		GroupID: groupID,
+		ActorID: &currentUser.ID,
+		Action:  data.ActivityMemberJoined,
+		UserID:  &input.UserID,
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully added to group"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func (app *application) removeGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
-	groupID, err := app.readIDParam(r, "group_id")
+func (app *application) showGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "user_id")
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	group, err := app.models.Groups.Get(groupID)
+	member, err := app.models.GroupMembers.Get(ids["group_id"], ids["user_id"])
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -57,21 +90,60 @@ func (app *application) removeGroupMemberHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	userID, err := app.readIDParam(r, "user_id")
+	err = app.writeJSON(w, http.StatusOK, envelope{"member": member}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readManagedMember looks up another member of the group on behalf of the
+// current member, who needs to be allowed to manage members and to outrank
+// them. If not, it sends the response itself and returns false.
+func (app *application) readManagedMember(w http.ResponseWriter, r *http.Request, groupID, userID int64) (*data.GroupMember, bool) {
+	actor := app.contextGetGroupMember(r)
+
+	member, err := app.models.GroupMembers.Get(groupID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !data.RoleHasPermission(actor.Role, data.PermissionManageMembers) || !data.RoleOutranks(actor.Role, member.Role) {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	return member, true
+}
+
+func (app *application) removeGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "user_id")
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
+	actor := app.contextGetGroupMember(r)
 
-	// ! cuidao si es nil el pointer
-	if currentUser.ID != userID && currentUser.ID != *group.CreatedBy {
-		app.invalidUserResponse(w, r)
+	// Anyone can leave a group except its owner, who has to hand it over
+	// first. Removing somebody else is up to those who outrank them.
+	if ids["user_id"] == actor.UserID {
+		if actor.Role == data.RoleOwner {
+			v := validator.New()
+			v.AddError("user_id", "the owner must transfer ownership before leaving the group")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	} else if _, ok := app.readManagedMember(w, r, ids["group_id"], ids["user_id"]); !ok {
 		return
 	}
 
-	err = app.models.GroupMembers.SoftDelete(groupID, userID)
+	err = app.models.GroupMembers.SoftDelete(ids["group_id"], ids["user_id"], &actor.UserID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -82,12 +154,141 @@ func (app *application) removeGroupMemberHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	userID := ids["user_id"]
+
+	app.recordActivity(&data.Activity{
+		GroupID: ids["group_id"],
+		ActorID: &actor.UserID,
+		Action:  data.ActivityMemberRemoved,
+		UserID:  &userID,
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully removed from group"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+func (app *application) updateGroupMemberRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	member, ok := app.readManagedMember(w, r, ids["group_id"], ids["user_id"])
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	actor := app.contextGetGroupMember(r)
+
+	v := validator.New()
+
+	v.Check(validator.PermittedValue(input.Role, data.RoleAdmin, data.RoleMember, data.RoleViewer), "role", "must be admin, member or viewer")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Nobody can hand out a role as high as their own.
+	if !data.RoleOutranks(actor.Role, input.Role) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	member.Role = input.Role
+
+	err = app.models.GroupMembers.UpdateRole(member, &actor.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordActivity(&data.Activity{
+		GroupID: member.GroupID,
+		ActorID: &actor.UserID,
+		Action:  data.ActivityMemberRoleChanged,
+		UserID:  &member.UserID,
+		Details: map[string]any{"role": member.Role},
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"member": member}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) transferGroupOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	actor := app.contextGetGroupMember(r)
+
+	v := validator.New()
+
+	v.Check(input.UserID > 0, "user_id", "must be a positive integer")
+	v.Check(input.UserID != actor.UserID, "user_id", "must not be the current owner")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.GroupMembers.TransferOwnership(groupID, actor.UserID, input.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("user_id", "must be an active member of the group")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordActivity(&data.Activity{
+		GroupID: groupID,
+		ActorID: &actor.UserID,
+		Action:  data.ActivityOwnershipTransferred,
+		UserID:  &input.UserID,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "group ownership successfully transferred"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) reinstateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 	groupID, err := app.readIDParam(r, "group_id")
 	if err != nil {
@@ -111,12 +312,26 @@ func (app *application) reinstateGroupMemberHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = app.models.GroupMembers.ReinstateMember(groupID, userID)
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.GroupMembers.ReinstateMember(groupID, userID, &currentUser.ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	app.recordActivity(&data.Activity{
+		GroupID: groupID,
+		ActorID: &currentUser.ID,
+		Action:  data.ActivityMemberReinstated,
+		UserID:  &userID,
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "Member reinstated successfully"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
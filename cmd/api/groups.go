@@ -48,7 +48,8 @@ func (app *application) listGroupsHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) createGroupHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name string `json:"name"`
+		Name     string `json:"name"`
+		Currency string `json:"currency"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -57,11 +58,16 @@ func (app *application) createGroupHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if input.Currency == "" {
+		input.Currency = data.DefaultCurrency
+	}
+
 	currentUser := app.contextGetUser(r)
 
 	// ! cuidao si es nil el pointer
 	group := &data.Group{
 		Name:      input.Name,
+		Currency:  input.Currency,
 		CreatedBy: &currentUser.ID,
 	}
 
@@ -129,16 +135,8 @@ func (app *application) updateGroupHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	// ! cuidao si es nil el pointer
-	if currentUser.ID != *group.CreatedBy {
-		app.invalidUserResponse(w, r)
-		return
-	}
-
 	var input struct {
-		Name string `json:"name"`
+		Name *string `json:"name"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -147,13 +145,20 @@ func (app *application) updateGroupHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Groups.Update(group)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	if input.Name != nil {
+		group.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateGroup(v, group); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"group": group}, nil)
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Groups.Update(group, &currentUser.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -163,6 +168,11 @@ func (app *application) updateGroupHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"group": group}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *application) deleteGroupHandler(w http.ResponseWriter, r *http.Request) {
@@ -172,26 +182,9 @@ func (app *application) deleteGroupHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	group, err := app.models.Groups.Get(id)
-	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundResponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
-		return
-	}
-
 	currentUser := app.contextGetUser(r)
 
-	// ! cuidao si es nil el pointer
-	if currentUser.ID != *group.CreatedBy {
-		app.invalidUserResponse(w, r)
-		return
-	}
-
-	err = app.models.Groups.Delete(id)
+	err = app.models.Groups.Delete(id, &currentUser.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
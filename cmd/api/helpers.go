@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/mailer"
 	"github.com/manuelam2003/triclone/internal/validator"
 )
 
@@ -137,6 +141,60 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// readDate reads an optional YYYY-MM-DD date from the query string, returning
+// nil if it is absent.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) *data.Date {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	date, err := data.ParseDate(s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return nil
+	}
+
+	return &date
+}
+
+// readTime reads an optional RFC 3339 timestamp from the query string,
+// returning nil if it is absent.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be a timestamp in RFC 3339 format")
+		return nil
+	}
+
+	return &t
+}
+
+// validateDateRange checks that a from/to range read with readDate is not
+// reversed.
+func (app *application) validateDateRange(v *validator.Validator, from, to *data.Date) {
+	if from != nil && to != nil {
+		v.Check(!to.Before(from.Time), "to", "must not be before from")
+	}
+}
+
+// clientIP returns the address the request came from, without the port.
+func (app *application) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
 func (app *application) checkUserMembership(w http.ResponseWriter, r *http.Request, userID, groupID int64) (bool, error) {
 	isMember, err := app.models.GroupMembers.UserBelongsToGroup(userID, groupID)
 	if err != nil {
@@ -149,3 +207,36 @@ func (app *application) checkUserMembership(w http.ResponseWriter, r *http.Reque
 	}
 	return true, nil
 }
+
+// background runs fn in its own goroutine, which serve waits for before the
+// server shuts down. A panic in fn is logged instead of crashing the server.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// sendEmail renders templateFile for recipient and sends it in the
+// background, so the response does not wait on the mail server.
+func (app *application) sendEmail(recipient, templateFile string, data any) {
+	app.background(func() {
+		msg, err := mailer.NewMessage(recipient, templateFile, data)
+		if err == nil {
+			err = app.mailer.Send(msg)
+		}
+
+		if err != nil {
+			app.logger.Error(err.Error(), "template", templateFile)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/jwt"
+)
+
+// accessClaims are the claims of the access tokens issued in the stateless
+// authentication mode. They carry everything the handlers need to know about
+// the user, so the user is not looked up on every request. Changes to the
+// user only show up in the next access token.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	SessionID int64  `json:"sid"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Activated bool   `json:"activated"`
+	Admin     bool   `json:"admin,omitempty"`
+}
+
+// signAccessToken issues a signed access token for user in the session with
+// sessionID.
+func (app *application) signAccessToken(user *data.User, sessionID int64) (*data.Token, error) {
+	claims := &accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.FormatInt(user.ID, 10)},
+		SessionID:        sessionID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Activated:        user.Activated,
+		Admin:            user.IsAdmin,
+	}
+
+	expiry := time.Now().Add(app.config.auth.accessTokenTTL)
+
+	plaintext, err := app.jwt.Sign(claims, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.Token{Plaintext: plaintext, UserID: user.ID, Expiry: expiry, Scope: data.ScopeAuthentication, FamilyID: sessionID}, nil
+}
+
+// userForAccessToken checks a signed access token and returns the user and
+// session it was issued for.
+func (app *application) userForAccessToken(token string) (*data.User, int64, error) {
+	var claims accessClaims
+
+	err := app.jwt.Verify(token, &claims)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, 0, jwt.ErrInvalidToken
+	}
+
+	user := &data.User{
+		ID:        id,
+		Name:      claims.Name,
+		Email:     claims.Email,
+		Activated: claims.Activated,
+		IsAdmin:   claims.Admin,
+	}
+
+	return user, claims.SessionID, nil
+}
@@ -6,18 +6,23 @@ import (
 	"flag"
 	"log/slog"
 	"os"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/jwt"
+	"github.com/manuelam2003/triclone/internal/mailer"
+	"github.com/manuelam2003/triclone/internal/storage"
 )
 
 const version = "1.0.0"
 
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port     int
+	env      string
+	logLevel slog.Level
+	db       struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
@@ -28,12 +33,43 @@ type config struct {
 		burst   int
 		enabled bool
 	}
+	scheduler struct {
+		interval time.Duration
+		enabled  bool
+	}
+	storage struct {
+		dir           string
+		maxUploadSize int64
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
+	outboxDir string
+	auth      struct {
+		mode            string
+		accessTokenTTL  time.Duration
+		refreshTokenTTL time.Duration
+	}
+	jwt struct {
+		keys         string
+		signingKeyID string
+		issuer       string
+		audience     string
+	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	storage storage.Storage
+	mailer  mailer.Mailer
+	jwt     *jwt.Keyset
+	wg      sync.WaitGroup
 }
 
 func main() {
@@ -41,6 +77,7 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.TextVar(&cfg.logLevel, "log-level", slog.LevelInfo, "Minimum log level (DEBUG|INFO|WARN|ERROR)")
 
 	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("TRICLONE_DB_DSN"), "PostgreSQL DSN")
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
@@ -51,9 +88,31 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
 
+	flag.DurationVar(&cfg.scheduler.interval, "scheduler-interval", time.Hour, "How often to create due recurring expenses")
+	flag.BoolVar(&cfg.scheduler.enabled, "scheduler-enabled", true, "Enable the recurring expenses scheduler")
+
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory where attachments are stored")
+	flag.Int64Var(&cfg.storage.maxUploadSize, "storage-max-upload-size", 10<<20, "Maximum attachment size in bytes")
+
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "", "SMTP host, emails are written to the outbox directory when empty")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 587, "SMTP port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", os.Getenv("TRICLONE_SMTP_USERNAME"), "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("TRICLONE_SMTP_PASSWORD"), "SMTP password")
+	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Triclone <no-reply@triclone.local>", "SMTP sender")
+	flag.StringVar(&cfg.outboxDir, "outbox-dir", "./outbox", "Directory where emails are written when no SMTP host is set")
+
+	flag.StringVar(&cfg.auth.mode, "auth-mode", "database", "How authentication tokens are checked (database|jwt)")
+	flag.DurationVar(&cfg.auth.accessTokenTTL, "auth-access-token-ttl", 15*time.Minute, "How long authentication tokens are valid")
+	flag.DurationVar(&cfg.auth.refreshTokenTTL, "auth-refresh-token-ttl", 30*24*time.Hour, "How long refresh tokens are valid")
+
+	flag.StringVar(&cfg.jwt.keys, "jwt-keys", os.Getenv("TRICLONE_JWT_KEYS"), "Comma-separated JWT keys in the form id:algorithm:base64")
+	flag.StringVar(&cfg.jwt.signingKeyID, "jwt-signing-key", "", "ID of the JWT key to sign with, the first key when empty")
+	flag.StringVar(&cfg.jwt.issuer, "jwt-issuer", "triclone", "JWT issuer")
+	flag.StringVar(&cfg.jwt.audience, "jwt-audience", "triclone-api", "JWT audience")
+
 	flag.Parse()
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.logLevel}))
 
 	db, err := openDB(cfg)
 	if err != nil {
@@ -65,10 +124,46 @@ func main() {
 
 	logger.Info("database connection pool established")
 
+	store, err := storage.NewLocal(cfg.storage.dir)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	var mail mailer.Mailer
+
+	if cfg.smtp.host != "" {
+		mail = mailer.NewSMTP(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender)
+	} else {
+		mail, err = mailer.NewOutbox(cfg.outboxDir, cfg.smtp.sender, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var keyset *jwt.Keyset
+
+	switch cfg.auth.mode {
+	case "database":
+	case "jwt":
+		keyset, err = newKeyset(cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	default:
+		logger.Error("invalid auth mode", "mode", cfg.auth.mode)
+		os.Exit(1)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db, logger, store),
+		storage: store,
+		mailer:  mail,
+		jwt:     keyset,
 	}
 
 	err = app.serve()
@@ -78,6 +173,15 @@ func main() {
 	}
 }
 
+func newKeyset(cfg config) (*jwt.Keyset, error) {
+	keys, err := jwt.ParseKeys(cfg.jwt.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.NewKeyset(keys, cfg.jwt.signingKeyID, cfg.jwt.issuer, cfg.jwt.audience)
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
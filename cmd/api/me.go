@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+)
+
+func (app *application) showMyBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := app.contextGetUser(r)
+
+	positions, err := app.models.Balances.CalculateUserPositions(currentUser.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"totals": data.SummarizePositions(positions),
+		"groups": positions,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listMySessionsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := app.contextGetUser(r)
+
+	sessions, err := app.models.Tokens.GetAllSessionsForUser(currentUser.ID, app.contextGetSessionID(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMySessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := app.readIDParam(r, "session_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Tokens.DeleteSession(currentUser.ID, sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "session successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOtherSessionsHandler signs the user out everywhere except on the
+// client making the request.
+func (app *application) deleteOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteOtherSessions(currentUser.ID, app.contextGetSessionID(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "all other sessions successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
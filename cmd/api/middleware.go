@@ -105,6 +105,22 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		token := headerParts[1]
 
+		// In the stateless mode the token is signed and carries the user, so
+		// the database is not needed.
+		if app.jwt != nil {
+			user, sessionID, err := app.userForAccessToken(token)
+			if err != nil {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			r = app.contextSetUser(r, user)
+			r = app.contextSetSessionID(r, sessionID)
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		v := validator.New()
 
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
@@ -112,7 +128,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, sessionID, err := app.models.Users.GetForSession(token)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -123,7 +139,15 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		// Failing to record when the session was last used is no reason to
+		// turn the request away.
+		err = app.models.Tokens.Touch(token)
+		if err != nil {
+			app.logger.Error(err.Error(), "session_id", sessionID)
+		}
+
 		r = app.contextSetUser(r, user)
+		r = app.contextSetSessionID(r, sessionID)
 
 		next.ServeHTTP(w, r)
 	})
@@ -156,3 +180,55 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 
 	return app.requireAuthenticatedUser(fn)
 }
+
+func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.IsAdmin {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
+// requireGroupPermission only lets through active members of the group in the
+// URL whose role grants permission. Their membership is put in the request
+// context for the handler.
+func (app *application) requireGroupPermission(permission data.Permission, next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groupID, err := app.readIDParam(r, "group_id")
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		user := app.contextGetUser(r)
+
+		member, err := app.models.GroupMembers.Get(groupID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.forbiddenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !member.IsActive || !data.RoleHasPermission(member.Role, permission) {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		r = app.contextSetGroupMember(r, member)
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func (app *application) listRecurringExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "amount", "description", "next_occurrence", "-id", "-amount", "-description", "-next_occurrence"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recurringExpenses, metadata, err := app.models.RecurringExpenses.GetAllForGroup(groupID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_expenses": recurringExpenses, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showRecurringExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "recurring_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	recurring, err := app.models.RecurringExpenses.Get(ids["group_id"], ids["recurring_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_expense": recurring}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createRecurringExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Amount      data.Money  `json:"amount"`
+		Currency    string      `json:"currency"`
+		Description string      `json:"description"`
+		CategoryID  *int64      `json:"category_id"`
+		Frequency   string      `json:"frequency"`
+		StartDate   *data.Date  `json:"start_date"`
+		EndDate     *data.Date  `json:"end_date"`
+		Split       *data.Split `json:"split"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Currency == "" {
+		input.Currency = group.Currency
+	}
+
+	if input.StartDate == nil {
+		today := data.Today()
+		input.StartDate = &today
+	}
+
+	v := validator.New()
+
+	if input.Split == nil {
+		v.AddError("split", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The rate is looked up again every time an occurrence is created, this
+	// only makes sure that one exists to begin with.
+	_, err = app.lookupExchangeRate(v, group, input.Currency)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	recurring := &data.RecurringExpense{
+		GroupID:     groupID,
+		Amount:      input.Amount,
+		Currency:    input.Currency,
+		Description: input.Description,
+		PaidBy:      &currentUser.ID,
+		CategoryID:  input.CategoryID,
+		Frequency:   input.Frequency,
+		StartDate:   *input.StartDate,
+		EndDate:     input.EndDate,
+		Split:       *input.Split,
+	}
+
+	if data.ValidateRecurringExpense(v, recurring); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.validateExpenseCategory(v, groupID, recurring.CategoryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.validateSplitMembers(v, groupID, &recurring.Split)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recurring.ScheduleNext()
+
+	err = app.models.RecurringExpenses.Insert(recurring)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/groups/%d/recurring-expenses/%d", groupID, recurring.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"recurring_expense": recurring}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateRecurringExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "recurring_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	recurring, err := app.models.RecurringExpenses.Get(ids["group_id"], ids["recurring_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Amount      *data.Money `json:"amount"`
+		Description *string     `json:"description"`
+		CategoryID  *int64      `json:"category_id"`
+		EndDate     *data.Date  `json:"end_date"`
+		Split       *data.Split `json:"split"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Amount != nil {
+		recurring.Amount = *input.Amount
+	}
+
+	if input.Description != nil {
+		recurring.Description = *input.Description
+	}
+
+	// As with expenses, a category_id of 0 removes the category.
+	if input.CategoryID != nil {
+		recurring.CategoryID = input.CategoryID
+		if *input.CategoryID == 0 {
+			recurring.CategoryID = nil
+		}
+	}
+
+	if input.EndDate != nil {
+		recurring.EndDate = input.EndDate
+	}
+
+	if input.Split != nil {
+		recurring.Split = *input.Split
+	}
+
+	v := validator.New()
+
+	if data.ValidateRecurringExpense(v, recurring); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.validateExpenseCategory(v, ids["group_id"], recurring.CategoryID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if input.Split != nil {
+		err = app.validateSplitMembers(v, ids["group_id"], &recurring.Split)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recurring.ScheduleNext()
+
+	err = app.models.RecurringExpenses.Update(recurring)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_expense": recurring}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteRecurringExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "recurring_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.RecurringExpenses.Delete(ids["group_id"], ids["recurring_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "recurring expense successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
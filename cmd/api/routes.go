@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/manuelam2003/triclone/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -19,39 +20,106 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/users/:user_id", app.showUserHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.createUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/password", app.updateUserPasswordHandler)
 	router.HandlerFunc(http.MethodPatch, "/v1/users/:user_id", app.requireActivatedUser(app.updateUserHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/users/:user_id", app.requireActivatedUser(app.deleteUserHandler))
 
 	router.HandlerFunc(http.MethodGet, "/v1/groups", app.listGroupsHandler)
 	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id", app.showGroupHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/groups", app.requireActivatedUser(app.createGroupHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id", app.requireActivatedUser(app.updateGroupHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id", app.requireActivatedUser(app.deleteGroupHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id", app.requireGroupPermission(data.PermissionManageGroup, app.updateGroupHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id", app.requireGroupPermission(data.PermissionOwn, app.deleteGroupHandler))
 
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/members", app.listGroupMembersHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/members", app.requireActivatedUser(app.addGroupMemberHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/members/:user_id", app.removeGroupMemberHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/members/:user_id", app.requireActivatedUser(app.reinstateGroupMemberHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/members", app.requireGroupPermission(data.PermissionView, app.listGroupMembersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/members", app.requireGroupPermission(data.PermissionManageMembers, app.addGroupMemberHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/members/:user_id", app.requireGroupPermission(data.PermissionView, app.removeGroupMemberHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/members/:user_id", app.requireGroupPermission(data.PermissionManageMembers, app.reinstateGroupMemberHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/members/:user_id", app.requireGroupPermission(data.PermissionView, app.showGroupMemberHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id/members/:user_id", app.requireGroupPermission(data.PermissionManageMembers, app.updateGroupMemberRoleHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/owner", app.requireGroupPermission(data.PermissionOwn, app.transferGroupOwnershipHandler))
 
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses", app.requireActivatedUser(app.listGroupExpensesHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id", app.requireActivatedUser(app.showGroupExpenseHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses", app.requireActivatedUser(app.createGroupExpenseHandler))
-	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/expenses/:expense_id", app.requireActivatedUser(app.updateGroupExpenseHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id", app.requireActivatedUser(app.deleteGroupExpenseHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/invites", app.requireGroupPermission(data.PermissionManageMembers, app.listGroupInvitesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/invites", app.requireGroupPermission(data.PermissionManageMembers, app.createGroupInviteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/invites/:invite_id", app.requireGroupPermission(data.PermissionManageMembers, app.deleteGroupInviteHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/invites/redeem", app.requireActivatedUser(app.redeemGroupInviteHandler))
 
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id/participants", app.requireActivatedUser(app.listExpenseParticipantsHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses/:expense_id/participants", app.requireActivatedUser(app.addExpenseParticipantsHandler))
-	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/expenses/:expense_id/participants/:participant_id", app.requireActivatedUser(app.updateExpenseParticipantHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id/participants/:participant_id", app.requireActivatedUser(app.deleteExpenseParticipantHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses", app.requireGroupPermission(data.PermissionView, app.listGroupExpensesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id", app.requireGroupPermission(data.PermissionView, app.showGroupExpenseHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses", app.requireGroupPermission(data.PermissionWrite, app.createGroupExpenseHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/expenses/:expense_id", app.requireGroupPermission(data.PermissionWrite, app.updateGroupExpenseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id", app.requireGroupPermission(data.PermissionDelete, app.deleteGroupExpenseHandler))
 
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settlements", app.requireActivatedUser(app.listSettlementsHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settlements/:settlement_id", app.requireActivatedUser(app.showSettlementHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/settlements", app.requireActivatedUser(app.addSettlementHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/settlements/:settlement_id", app.requireActivatedUser(app.deleteSettlementHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id/participants", app.requireGroupPermission(data.PermissionView, app.listExpenseParticipantsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses/:expense_id/participants", app.requireGroupPermission(data.PermissionWrite, app.addExpenseParticipantsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/expenses/:expense_id/participants/:participant_id", app.requireGroupPermission(data.PermissionWrite, app.updateExpenseParticipantHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id/participants/:participant_id", app.requireGroupPermission(data.PermissionWrite, app.deleteExpenseParticipantHandler))
 
-	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/balance", app.requireActivatedUser(app.groupBalanceHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id/attachments", app.requireGroupPermission(data.PermissionView, app.listAttachmentsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id/attachments/:attachment_id", app.requireGroupPermission(data.PermissionView, app.downloadAttachmentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses/:expense_id/attachments", app.requireGroupPermission(data.PermissionWrite, app.uploadAttachmentHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id/attachments/:attachment_id", app.requireGroupPermission(data.PermissionWrite, app.deleteAttachmentHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/expenses/:expense_id/comments", app.requireGroupPermission(data.PermissionView, app.listCommentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/expenses/:expense_id/comments", app.requireGroupPermission(data.PermissionWrite, app.createCommentHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id/expenses/:expense_id/comments/:comment_id", app.requireGroupPermission(data.PermissionWrite, app.updateCommentHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/expenses/:expense_id/comments/:comment_id", app.requireGroupPermission(data.PermissionWrite, app.deleteCommentHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settlements", app.requireGroupPermission(data.PermissionView, app.listSettlementsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settlements/:settlement_id", app.requireGroupPermission(data.PermissionView, app.showSettlementHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/settlements", app.requireGroupPermission(data.PermissionWrite, app.addSettlementHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/settlements/:settlement_id", app.requireGroupPermission(data.PermissionDelete, app.deleteSettlementHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settlements/:settlement_id/comments", app.requireGroupPermission(data.PermissionView, app.listCommentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/settlements/:settlement_id/comments", app.requireGroupPermission(data.PermissionWrite, app.createCommentHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id/settlements/:settlement_id/comments/:comment_id", app.requireGroupPermission(data.PermissionWrite, app.updateCommentHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/settlements/:settlement_id/comments/:comment_id", app.requireGroupPermission(data.PermissionWrite, app.deleteCommentHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/trash/expenses", app.requireGroupPermission(data.PermissionView, app.listTrashedExpensesHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/trash/expenses/:expense_id", app.requireGroupPermission(data.PermissionDelete, app.restoreExpenseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/trash/expenses/:expense_id", app.requireGroupPermission(data.PermissionDelete, app.purgeExpenseHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/trash/settlements", app.requireGroupPermission(data.PermissionView, app.listTrashedSettlementsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/trash/settlements/:settlement_id", app.requireGroupPermission(data.PermissionDelete, app.restoreSettlementHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/trash/settlements/:settlement_id", app.requireGroupPermission(data.PermissionDelete, app.purgeSettlementHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/exchange-rates", app.requireGroupPermission(data.PermissionView, app.listExchangeRatesHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/groups/:group_id/exchange-rates/:currency", app.requireGroupPermission(data.PermissionManageGroup, app.putExchangeRateHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/exchange-rates/:currency", app.requireGroupPermission(data.PermissionManageGroup, app.deleteExchangeRateHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/recurring-expenses", app.requireGroupPermission(data.PermissionView, app.listRecurringExpensesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/recurring-expenses/:recurring_id", app.requireGroupPermission(data.PermissionView, app.showRecurringExpenseHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/recurring-expenses", app.requireGroupPermission(data.PermissionWrite, app.createRecurringExpenseHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id/recurring-expenses/:recurring_id", app.requireGroupPermission(data.PermissionWrite, app.updateRecurringExpenseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/recurring-expenses/:recurring_id", app.requireGroupPermission(data.PermissionWrite, app.deleteRecurringExpenseHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/categories", app.requireGroupPermission(data.PermissionView, app.listCategoriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/categories/:category_id", app.requireGroupPermission(data.PermissionView, app.showCategoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/categories", app.requireGroupPermission(data.PermissionWrite, app.createCategoryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/groups/:group_id/categories/:category_id", app.requireGroupPermission(data.PermissionWrite, app.updateCategoryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/groups/:group_id/categories/:category_id", app.requireGroupPermission(data.PermissionWrite, app.deleteCategoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/balance", app.requireGroupPermission(data.PermissionView, app.groupBalanceHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/balance/:user_id", app.requireGroupPermission(data.PermissionView, app.userBalanceHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/debts", app.requireGroupPermission(data.PermissionView, app.groupDebtsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/settle-up", app.requireGroupPermission(data.PermissionView, app.groupSettleUpHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/settle-up", app.requireGroupPermission(data.PermissionWrite, app.recordSettleUpHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/activity", app.requireGroupPermission(data.PermissionView, app.listGroupActivityHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/members/:user_id/ledger", app.requireGroupPermission(data.PermissionView, app.listMemberLedgerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/groups/:group_id/ledger/verify", app.requireGroupPermission(data.PermissionView, app.verifyLedgerHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/groups/:group_id/ledger/rebuild", app.requireGroupPermission(data.PermissionManageGroup, app.rebuildLedgerHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/me/balances", app.requireActivatedUser(app.showMyBalancesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/me/sessions", app.requireAuthenticatedUser(app.listMySessionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/sessions", app.requireAuthenticatedUser(app.deleteOtherSessionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/me/sessions/:session_id", app.requireAuthenticatedUser(app.deleteMySessionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/audit-log", app.requireAdmin(app.listAuditLogHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
 
 	return app.recoverPanic(app.rateLimit(app.authenticate(router)))
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/data"
+)
+
+// runScheduler materializes due recurring expenses once at startup and then
+// every interval, until ctx is cancelled. A run that is in progress when ctx
+// is cancelled stops after the recurring expense it is working on.
+func (app *application) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.materializeRecurringExpenses(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) materializeRecurringExpenses(ctx context.Context) {
+	today := data.Today()
+
+	ids, err := app.models.RecurringExpenses.GetDue(today)
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		expenses, err := app.models.RecurringExpenses.Materialize(id, today)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrMissingExchangeRate):
+				app.logger.Warn("skipping recurring expense without an exchange rate", "recurring_expense_id", id)
+			default:
+				app.logger.Error(err.Error(), "recurring_expense_id", id)
+			}
+			continue
+		}
+
+		for _, expense := range expenses {
+			app.recordActivity(expenseActivity(data.ActivityExpenseCreated, nil, expense))
+		}
+
+		if len(expenses) > 0 {
+			app.logger.Info("materialized recurring expense", "recurring_expense_id", id, "expenses", len(expenses))
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -24,6 +25,19 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
+	var scheduler sync.WaitGroup
+
+	if app.config.scheduler.enabled {
+		scheduler.Add(1)
+		go func() {
+			defer scheduler.Done()
+			app.runScheduler(schedulerCtx, app.config.scheduler.interval)
+		}()
+	}
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -34,7 +48,18 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+
+		app.logger.Info("stopping scheduler")
+
+		stopScheduler()
+		scheduler.Wait()
+
+		app.logger.Info("completing background tasks")
+
+		app.wg.Wait()
+
+		shutdownError <- err
 	}()
 
 	app.logger.Info("starting server", "addr", srv.Addr, "env", app.config.env)
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/manuelam2003/triclone/internal/data"
 	"github.com/manuelam2003/triclone/internal/validator"
@@ -15,30 +16,30 @@ func (app *application) listSettlementsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	var input struct {
+		From *data.Date
+		To   *data.Date
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
+	input.From = app.readDate(qs, "from", v)
+	input.To = app.readDate(qs, "to", v)
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "settled_at")
 	input.Filters.SortSafelist = []string{"id", "amount", "settled_at", "payer_id", "payee_id", "-id", "-amount", "-settled_at", "-payer_id", "-payee_id"}
 
-	if !v.Valid() {
+	app.validateDateRange(v, input.From, input.To)
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	settlements, metadata, err := app.models.Settlements.GetAllForGroup(groupID, input.Filters)
+	settlements, metadata, err := app.models.Settlements.GetAllForGroup(groupID, input.From, input.To, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -57,13 +58,6 @@ func (app *application) showSettlementHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	currentUser := app.contextGetUser(r)
-
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	settlementID, err := app.readIDParam(r, "settlement_id")
 	if err != nil {
 		app.notFoundResponse(w, r)
@@ -95,15 +89,12 @@ func (app *application) addSettlementHandler(w http.ResponseWriter, r *http.Requ
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	var input struct {
-		PayerID int64   `json:"payer_id"`
-		PayeeID int64   `json:"payee_id"`
-		Amount  float64 `json:"amount"`
+		PayerID   int64      `json:"payer_id"`
+		PayeeID   int64      `json:"payee_id"`
+		Amount    data.Money `json:"amount"`
+		Currency  string     `json:"currency"`
+		SettledAt *time.Time `json:"settled_at"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -136,11 +127,41 @@ func (app *application) addSettlementHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	group, err := app.models.Groups.Get(groupID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if input.Currency == "" {
+		input.Currency = group.Currency
+	}
+
+	if input.SettledAt == nil {
+		now := time.Now()
+		input.SettledAt = &now
+	}
+
+	rate, err := app.lookupExchangeRate(v, group, input.Currency)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	settlement := &data.Settlement{
-		GroupID: groupID,
-		PayerID: &input.PayerID,
-		PayeeID: &input.PayeeID,
-		Amount:  input.Amount,
+		GroupID:      groupID,
+		PayerID:      &input.PayerID,
+		PayeeID:      &input.PayeeID,
+		Amount:       input.Amount,
+		Currency:     input.Currency,
+		ExchangeRate: rate,
+		BaseAmount:   input.Amount.Convert(rate),
+		SettledAt:    *input.SettledAt,
 	}
 
 	if data.ValidateSettlement(v, settlement); !v.Valid() {
@@ -148,12 +169,14 @@ func (app *application) addSettlementHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.models.Settlements.Insert(settlement)
+	err = app.models.Settlements.Insert(settlement, &currentUser.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.recordActivity(settlementActivity(data.ActivitySettlementCreated, &currentUser.ID, settlement))
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"settlement": settlement}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -169,18 +192,13 @@ func (app *application) deleteSettlementHandler(w http.ResponseWriter, r *http.R
 
 	currentUser := app.contextGetUser(r)
 
-	isMember, err := app.checkUserMembership(w, r, currentUser.ID, groupID)
-	if err != nil || !isMember {
-		return
-	}
-
 	settlementID, err := app.readIDParam(r, "settlement_id")
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Settlements.Delete(groupID, settlementID)
+	err = app.models.Settlements.SoftDelete(groupID, settlementID, &currentUser.ID)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			app.notFoundResponse(w, r)
@@ -190,7 +208,14 @@ func (app *application) deleteSettlementHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "settlement successfully deleted"}, nil)
+	app.recordActivity(&data.Activity{
+		GroupID:      groupID,
+		ActorID:      &currentUser.ID,
+		Action:       data.ActivitySettlementDeleted,
+		SettlementID: &settlementID,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "settlement successfully moved to trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
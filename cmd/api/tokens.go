@@ -53,13 +53,159 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	access, refresh, err := app.models.Tokens.NewSession(user.ID, app.databaseAccessTokenTTL(), app.config.auth.refreshTokenTTL, r.UserAgent(), app.clientIP(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if app.jwt != nil {
+		access, err = app.signAccessToken(user, refresh.FamilyID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": access, "refresh_token": refresh}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	access, refresh, err := app.models.Tokens.Refresh(input.TokenPlaintext, app.databaseAccessTokenTTL(), app.config.auth.refreshTokenTTL, r.UserAgent(), app.clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrTokenReused):
+			if errors.Is(err, data.ErrTokenReused) {
+				app.logger.Warn("refresh token reused, session revoked", "ip", app.clientIP(r))
+			}
+			v.AddError("refresh_token", "invalid or expired refresh token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.jwt != nil {
+		// The claims are filled in afresh, so changes to the user since the
+		// last access token was issued show up in the new one.
+		user, err := app.models.Users.GetByID(refresh.UserID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		access, err = app.signAccessToken(user, refresh.FamilyID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": access, "refresh_token": refresh}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// databaseAccessTokenTTL is how long the access tokens kept in the database
+// are valid. None are kept in the stateless mode, where access tokens are
+// signed instead.
+func (app *application) databaseAccessTokenTTL() time.Duration {
+	if app.jwt != nil {
+		return 0
+	}
+
+	return app.config.auth.accessTokenTTL
+}
+
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteSession(currentUser.ID, app.contextGetSessionID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been logged out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching email address found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !user.Activated {
+		v.AddError("email", "user account must be activated")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.sendEmail(user.Email, "password_reset.tmpl", map[string]any{
+		"passwordResetToken": token.Plaintext,
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": "an email will be sent to you containing password reset instructions"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
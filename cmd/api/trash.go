@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/manuelam2003/triclone/internal/data"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// readTrashFilters reads the pagination parameters shared by the trash
+// listings, which default to the most recently deleted first.
+func (app *application) readTrashFilters(r *http.Request, v *validator.Validator) data.Filters {
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "-deleted_at"),
+		SortSafelist: []string{"id", "deleted_at", "-id", "-deleted_at"},
+	}
+
+	data.ValidateFilters(v, filters)
+
+	return filters
+}
+
+func (app *application) listTrashedExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	filters := app.readTrashFilters(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	expenses, metadata, err := app.models.Expenses.GetAllDeleted(groupID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"expenses": expenses, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Expenses.Restore(ids["group_id"], ids["expense_id"], &currentUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	expense, err := app.models.Expenses.Get(ids["group_id"], ids["expense_id"])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordActivity(expenseActivity(data.ActivityExpenseRestored, &currentUser.ID, expense))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"expense": expense}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) purgeExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "expense_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Expenses.Purge(ids["group_id"], ids["expense_id"], &currentUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "expense permanently deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listTrashedSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := app.readIDParam(r, "group_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	filters := app.readTrashFilters(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	settlements, metadata, err := app.models.Settlements.GetAllDeleted(groupID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"settlements": settlements, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "settlement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Settlements.Restore(ids["group_id"], ids["settlement_id"], &currentUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	settlement, err := app.models.Settlements.Get(ids["settlement_id"], ids["group_id"])
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordActivity(settlementActivity(data.ActivitySettlementRestored, &currentUser.ID, settlement))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"settlement": settlement}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) purgeSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	ids, err := app.extractIDsFromRequest(r, "group_id", "settlement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	currentUser := app.contextGetUser(r)
+
+	err = app.models.Settlements.Purge(ids["group_id"], ids["settlement_id"], &currentUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "settlement permanently deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
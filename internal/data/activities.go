@@ -0,0 +1,135 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	ActivityExpenseCreated       = "expense_created"
+	ActivityExpenseUpdated       = "expense_updated"
+	ActivityExpenseDeleted       = "expense_deleted"
+	ActivityExpenseRestored      = "expense_restored"
+	ActivityParticipantsAdded    = "participants_added"
+	ActivityParticipantUpdated   = "participant_updated"
+	ActivityParticipantRemoved   = "participant_removed"
+	ActivitySettlementCreated    = "settlement_created"
+	ActivitySettlementDeleted    = "settlement_deleted"
+	ActivitySettlementRestored   = "settlement_restored"
+	ActivityMemberJoined         = "member_joined"
+	ActivityMemberRemoved        = "member_removed"
+	ActivityMemberReinstated     = "member_reinstated"
+	ActivityMemberRoleChanged    = "member_role_changed"
+	ActivityOwnershipTransferred = "ownership_transferred"
+)
+
+// Activity records a change made to a group. ActorID is who made it, or nil
+// for changes made by the server itself, and UserID is the member it affected,
+// if any. ExpenseID and SettlementID are kept after the record they point to
+// is deleted, so that the feed still makes sense.
+type Activity struct {
+	ID           int64          `json:"id"`
+	GroupID      int64          `json:"group_id"`
+	ActorID      *int64         `json:"actor_id"`
+	Action       string         `json:"action"`
+	ExpenseID    *int64         `json:"expense_id,omitempty"`
+	SettlementID *int64         `json:"settlement_id,omitempty"`
+	UserID       *int64         `json:"user_id,omitempty"`
+	Details      map[string]any `json:"details,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type ActivityModel struct {
+	DB *sql.DB
+}
+
+func (m ActivityModel) Insert(activity *Activity) error {
+	query := `
+		INSERT INTO activities (group_id, actor_id, action, expense_id, settlement_id, user_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	details, err := json.Marshal(activity.Details)
+	if err != nil {
+		return err
+	}
+
+	// A nil map marshals to null, which the column does not accept.
+	if activity.Details == nil {
+		details = []byte("{}")
+	}
+
+	args := []any{activity.GroupID, activity.ActorID, activity.Action, activity.ExpenseID, activity.SettlementID, activity.UserID, details}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&activity.ID, &activity.CreatedAt)
+}
+
+// GetAllForGroup returns a group's activity newest first. When since is set,
+// only activity recorded after it is returned.
+func (m ActivityModel) GetAllForGroup(groupID int64, since *time.Time, filters CursorFilters) ([]*Activity, CursorMetadata, error) {
+	query := `
+		SELECT id, group_id, actor_id, action, expense_id, settlement_id, user_id, details, created_at
+		FROM activities
+		WHERE group_id = $1
+		AND ($2 = 0 OR id < $2)
+		AND ($3::timestamp IS NULL OR created_at > $3)
+		ORDER BY id DESC
+		LIMIT $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// One more row than asked for is fetched to tell whether there is a next
+	// page.
+	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.Cursor, since, filters.PageSize+1)
+	if err != nil {
+		return nil, CursorMetadata{}, err
+	}
+	defer rows.Close()
+
+	activities := []*Activity{}
+
+	for rows.Next() {
+		var activity Activity
+		var details []byte
+
+		err := rows.Scan(
+			&activity.ID,
+			&activity.GroupID,
+			&activity.ActorID,
+			&activity.Action,
+			&activity.ExpenseID,
+			&activity.SettlementID,
+			&activity.UserID,
+			&details,
+			&activity.CreatedAt,
+		)
+		if err != nil {
+			return nil, CursorMetadata{}, err
+		}
+
+		if err := json.Unmarshal(details, &activity.Details); err != nil {
+			return nil, CursorMetadata{}, err
+		}
+
+		activities = append(activities, &activity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, CursorMetadata{}, err
+	}
+
+	metadata := CursorMetadata{PageSize: filters.PageSize}
+
+	if len(activities) > filters.PageSize {
+		activities = activities[:filters.PageSize]
+		metadata.NextCursor = activities[len(activities)-1].ID
+	}
+
+	return activities, metadata, nil
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/storage"
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// AttachmentContentTypes are the kinds of receipt that can be uploaded. The
+// type is sniffed from the file's contents rather than trusted from the client.
+var AttachmentContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+
+type Attachment struct {
+	ID          int64     `json:"id"`
+	ExpenseID   int64     `json:"expense_id"`
+	UploadedBy  *int64    `json:"uploaded_by"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func ValidateAttachment(v *validator.Validator, attachment *Attachment, maxSize int64) {
+	v.Check(attachment.Filename != "", "file", "must have a file name")
+	v.Check(len(attachment.Filename) <= 255, "file", "file name must not be more than 255 bytes long")
+	v.Check(validator.PermittedValue(attachment.ContentType, AttachmentContentTypes...), "file", "must be a JPEG, PNG, GIF or WebP image or a PDF document")
+	v.Check(attachment.Size > 0, "file", "must not be empty")
+	v.Check(attachment.Size <= maxSize, "file", fmt.Sprintf("must not be larger than %d bytes", maxSize))
+}
+
+// NewAttachmentKey returns a fresh, unguessable storage key for a file
+// attached to the expense.
+func NewAttachmentKey(expenseID int64) (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("expenses/%d/%s", expenseID, hex.EncodeToString(randomBytes)), nil
+}
+
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+func (m AttachmentModel) Insert(attachment *Attachment) error {
+	query := `
+		INSERT INTO attachments (expense_id, uploaded_by, filename, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	args := []any{attachment.ExpenseID, attachment.UploadedBy, attachment.Filename, attachment.ContentType, attachment.Size, attachment.StorageKey}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (m AttachmentModel) GetAllForExpense(expenseID int64) ([]*Attachment, error) {
+	query := `
+		SELECT id, expense_id, uploaded_by, filename, content_type, size, storage_key, created_at
+		FROM attachments
+		WHERE expense_id = $1
+		ORDER BY id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+
+	for rows.Next() {
+		var attachment Attachment
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.ExpenseID,
+			&attachment.UploadedBy,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.StorageKey,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (m AttachmentModel) Get(expenseID, attachmentID int64) (*Attachment, error) {
+	query := `
+		SELECT id, expense_id, uploaded_by, filename, content_type, size, storage_key, created_at
+		FROM attachments
+		WHERE id = $1 AND expense_id = $2`
+
+	var attachment Attachment
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, attachmentID, expenseID).Scan(
+		&attachment.ID,
+		&attachment.ExpenseID,
+		&attachment.UploadedBy,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &attachment, nil
+}
+
+func (m AttachmentModel) Delete(expenseID, attachmentID int64) error {
+	query := `
+		DELETE FROM attachments
+		WHERE id = $1 AND expense_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, attachmentID, expenseID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// storageKeys returns the keys of the files attached to the expenses matched
+// by where, which may refer to the expenses table as e.
+func storageKeys(ctx context.Context, q queryer, where string, args ...any) ([]string, error) {
+	query := `
+		SELECT a.storage_key
+		FROM attachments a
+		INNER JOIN expenses e ON e.id = a.expense_id
+		WHERE ` + where
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// removeFiles deletes files whose rows are already gone. Failures only leave
+// unreachable files behind, so they are logged rather than returned.
+func removeFiles(store storage.Storage, logger *slog.Logger, keys []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, key := range keys {
+		err := store.Delete(ctx, key)
+		if err != nil {
+			logger.Error(err.Error(), "storage_key", key)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditEntityExpense     = "expense"
+	AuditEntityGroup       = "group"
+	AuditEntityMember      = "group_member"
+	AuditEntityParticipant = "expense_participant"
+	AuditEntitySettlement  = "settlement"
+
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// auditSnapshotQueries select the group an entity belongs to and a JSON
+// snapshot of its row, locking the row for the rest of the transaction. An
+// expense's snapshot includes its participants, so deleting an expense keeps
+// a record of how it was split.
+var auditSnapshotQueries = map[string]string{
+	AuditEntityExpense: `
+		SELECT e.group_id, to_jsonb(e) || jsonb_build_object('participants', COALESCE((
+			SELECT jsonb_agg(to_jsonb(p) ORDER BY p.id)
+			FROM expense_participants p
+			WHERE p.expense_id = e.id
+		), '[]'::jsonb))
+		FROM expenses e
+		WHERE e.id = $1
+		FOR UPDATE OF e`,
+	AuditEntityParticipant: `
+		SELECT e.group_id, to_jsonb(p)
+		FROM expense_participants p
+		INNER JOIN expenses e ON e.id = p.expense_id
+		WHERE p.id = $1
+		FOR UPDATE OF p`,
+	AuditEntitySettlement: `
+		SELECT s.group_id, to_jsonb(s)
+		FROM settlements s
+		WHERE s.id = $1
+		FOR UPDATE OF s`,
+	AuditEntityGroup: `
+		SELECT g.id, to_jsonb(g)
+		FROM groups g
+		WHERE g.id = $1
+		FOR UPDATE OF g`,
+	AuditEntityMember: `
+		SELECT gm.group_id, to_jsonb(gm)
+		FROM group_members gm
+		WHERE gm.id = $1
+		FOR UPDATE OF gm`,
+}
+
+// AuditEntry records one change to a group, a membership or a financial
+// record. Before is empty for creations and After is empty for deletions.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   *int64          `json:"actor_id"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	GroupID   int64           `json:"group_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type AuditFilters struct {
+	ActorID  int64
+	Entity   string
+	EntityID int64
+	GroupID  int64
+	From     *Date
+	To       *Date
+}
+
+type auditSnapshot struct {
+	groupID int64
+	data    []byte
+}
+
+func takeAuditSnapshot(ctx context.Context, q queryer, entity string, id int64) (*auditSnapshot, error) {
+	if id == 0 {
+		return nil, nil
+	}
+
+	var snapshot auditSnapshot
+
+	err := q.QueryRowContext(ctx, auditSnapshotQueries[entity], id).Scan(&snapshot.groupID, &snapshot.data)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &snapshot, nil
+}
+
+// audited runs change inside tx and records it in the audit log along with
+// snapshots of the entity from before and after. id is read again once change
+// has run, so it may point at an ID that change fills in. Whether the change
+// was a creation, an update or a deletion is worked out from which snapshots
+// exist.
+func audited(ctx context.Context, tx *sql.Tx, actorID *int64, entity string, id *int64, change func() error) error {
+	before, err := takeAuditSnapshot(ctx, tx, entity, *id)
+	if err != nil {
+		return err
+	}
+
+	err = change()
+	if err != nil {
+		return err
+	}
+
+	after, err := takeAuditSnapshot(ctx, tx, entity, *id)
+	if err != nil {
+		return err
+	}
+
+	entry := &AuditEntry{ActorID: actorID, Entity: entity, EntityID: *id}
+
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		entry.Action = AuditActionCreate
+		entry.GroupID = after.groupID
+		entry.After = after.data
+	case after == nil:
+		entry.Action = AuditActionDelete
+		entry.GroupID = before.groupID
+		entry.Before = before.data
+	default:
+		entry.Action = AuditActionUpdate
+		entry.GroupID = after.groupID
+		entry.Before = before.data
+		entry.After = after.data
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity, entity_id, group_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	args := []any{entry.ActorID, entry.Action, entry.Entity, entry.EntityID, entry.GroupID, nullJSON(entry.Before), nullJSON(entry.After)}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// nullJSON stores a missing snapshot as NULL rather than as an empty value.
+func nullJSON(raw json.RawMessage) any {
+	if raw == nil {
+		return nil
+	}
+
+	return []byte(raw)
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) GetAll(auditFilters AuditFilters, filters Filters) ([]*AuditEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, actor_id, action, entity, entity_id, group_id, before, after, created_at
+		FROM audit_log
+		WHERE ($1 = 0 OR actor_id = $1)
+		AND ($2 = '' OR entity = $2)
+		AND ($3 = 0 OR entity_id = $3)
+		AND ($4 = 0 OR group_id = $4)
+		AND ($5::date IS NULL OR created_at >= $5)
+		AND ($6::date IS NULL OR created_at < $6::date + 1)
+		ORDER BY %s %s, id DESC
+		LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{
+		auditFilters.ActorID,
+		auditFilters.Entity,
+		auditFilters.EntityID,
+		auditFilters.GroupID,
+		auditFilters.From,
+		auditFilters.To,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after []byte
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.Entity,
+			&entry.EntityID,
+			&entry.GroupID,
+			&before,
+			&after,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entry.Before = before
+		entry.After = after
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
@@ -1,95 +1,375 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"log/slog"
+	"sort"
+	"time"
 )
 
 type Balance struct {
-	UserID  int64   `json:"user_id"`
-	Balance float64 `json:"balance"`
+	UserID  int64 `json:"user_id"`
+	Balance Money `json:"balance"`
+}
+
+type Transfer struct {
+	From   int64 `json:"from"`
+	To     int64 `json:"to"`
+	Amount Money `json:"amount"`
+}
+
+// SimplifyDebts returns a list of transfers that brings every balance back to
+// zero. It greedily matches the largest debtor with the largest creditor, which
+// settles a group of n members in at most n-1 transfers.
+func SimplifyDebts(balances []Balance) []Transfer {
+	var debtors, creditors []Balance
+
+	for _, balance := range balances {
+		switch {
+		case balance.Balance > 0:
+			debtors = append(debtors, balance)
+		case balance.Balance < 0:
+			creditors = append(creditors, Balance{UserID: balance.UserID, Balance: -balance.Balance})
+		}
+	}
+
+	byAmount := func(list []Balance) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].Balance != list[j].Balance {
+				return list[i].Balance > list[j].Balance
+			}
+			return list[i].UserID < list[j].UserID
+		}
+	}
+
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	transfers := []Transfer{}
+
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := min(debtors[i].Balance, creditors[j].Balance)
+
+		transfers = append(transfers, Transfer{
+			From:   debtors[i].UserID,
+			To:     creditors[j].UserID,
+			Amount: amount,
+		})
+
+		debtors[i].Balance -= amount
+		creditors[j].Balance -= amount
+
+		if debtors[i].Balance == 0 {
+			i++
+		}
+		if creditors[j].Balance == 0 {
+			j++
+		}
+	}
+
+	return transfers
+}
+
+type Debt struct {
+	DebtorID   int64 `json:"debtor_id"`
+	CreditorID int64 `json:"creditor_id"`
+	Amount     Money `json:"amount"`
+}
+
+// Counterparty is one side of a user's pairwise debts. As with Balance, a
+// positive amount means the user owes the counterparty.
+type Counterparty struct {
+	UserID  int64 `json:"user_id"`
+	Balance Money `json:"balance"`
+}
+
+func CounterpartiesFor(userID int64, debts []Debt) []Counterparty {
+	counterparties := []Counterparty{}
+
+	for _, debt := range debts {
+		switch userID {
+		case debt.DebtorID:
+			counterparties = append(counterparties, Counterparty{UserID: debt.CreditorID, Balance: debt.Amount})
+		case debt.CreditorID:
+			counterparties = append(counterparties, Counterparty{UserID: debt.DebtorID, Balance: -debt.Amount})
+		}
+	}
+
+	return counterparties
 }
 
 type BalanceModel struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Logger *slog.Logger
 }
 
+// CalculateGroupBalances computes every member's net balance in a single
+// aggregated query. Each participant's share is added to their balance and
+// subtracted from the payer's, and each settlement moves its amount from the
+// payer to the payee, so the balances of a group always add up to zero.
 func (m BalanceModel) CalculateGroupBalances(groupID int64) ([]Balance, error) {
-	expensesQuery := `
-        SELECT e.id, e.paid_by, p.user_id, SUM(p.amount_owed) AS total_owed, e.amount AS total_paid
-        FROM expense_participants p
-        INNER JOIN expenses e ON p.expense_id = e.id
-        WHERE e.group_id = $1
-        GROUP BY e.id, e.paid_by, p.user_id, e.amount
-    `
-
-	settlementsQuery := `
-        SELECT s.payer_id, s.payee_id, SUM(s.amount) AS settled_amount
-        FROM settlements s
-        WHERE s.group_id = $1
-        GROUP BY s.payer_id, s.payee_id
-    `
-
-	processedExpenses := make(map[int64]struct{}) // Set to track processed expense IDs
-	expenseMap := make(map[int64]float64)
-	var balances []Balance
-
-	// Query expenses and build the map of user balances based on what they owe
-	rows, err := m.DB.Query(expensesQuery, groupID)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	balances, err := groupBalances(ctx, m.DB, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Logger.Debug("calculated group balances", "group_id", groupID, "members", len(balances), "duration", time.Since(start))
+
+	return balances, nil
+}
+
+func groupBalances(ctx context.Context, q queryer, groupID int64) ([]Balance, error) {
+	query := `
+		WITH shares AS (
+			SELECT e.paid_by, p.user_id, ROUND(p.amount_owed * e.exchange_rate, 2) AS amount
+			FROM expense_participants p
+			INNER JOIN expenses e ON p.expense_id = e.id
+			WHERE e.group_id = $1 AND e.deleted_at IS NULL
+		)
+		SELECT entry.user_id, SUM(entry.amount)
+		FROM (
+			SELECT v.user_id, v.amount
+			FROM shares sh, LATERAL (VALUES (sh.user_id, sh.amount), (sh.paid_by, -sh.amount)) AS v(user_id, amount)
+			UNION ALL
+			SELECT v.user_id, v.amount
+			FROM settlements s, LATERAL (VALUES (s.payer_id, -s.base_amount), (s.payee_id, s.base_amount)) AS v(user_id, amount)
+			WHERE s.group_id = $1 AND s.deleted_at IS NULL
+		) entry
+		WHERE entry.user_id IS NOT NULL
+		GROUP BY entry.user_id
+		ORDER BY entry.user_id ASC`
+
+	rows, err := q.QueryContext(ctx, query, groupID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	balances := []Balance{}
+
 	for rows.Next() {
-		var expenseID, paidByUserID, participantUserID int64
-		var totalOwed, totalPaid float64
-		if err := rows.Scan(&expenseID, &paidByUserID, &participantUserID, &totalOwed, &totalPaid); err != nil {
+		var balance Balance
+		if err := rows.Scan(&balance.UserID, &balance.Balance); err != nil {
 			return nil, err
 		}
+		balances = append(balances, balance)
+	}
 
-		fmt.Printf("Expense: %d, Paid by: %d, User id: %d, Total owed: %.2f, Total amount: %.2f\n", expenseID, paidByUserID, participantUserID, totalOwed, totalPaid)
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Increase the balance of the participants by what they owe
-		expenseMap[participantUserID] += totalOwed
+	return balances, nil
+}
 
-		if _, exists := processedExpenses[expenseID]; !exists {
-			// Reduce the balance of the person who paid by the full amount of the expense
-			processedExpenses[expenseID] = struct{}{}
-			expenseMap[paidByUserID] -= totalPaid
-			continue
+// CalculatePairwiseDebts nets what every pair of members owes each other. A
+// participant owes the payer their share of an expense, and a settlement makes
+// the payee owe the payer the amount that was paid back.
+func (m BalanceModel) CalculatePairwiseDebts(groupID int64) ([]Debt, error) {
+	query := `
+		SELECT debtor_id, creditor_id, SUM(amount)
+		FROM (
+			SELECT p.user_id AS debtor_id, e.paid_by AS creditor_id, ROUND(p.amount_owed * e.exchange_rate, 2) AS amount
+			FROM expense_participants p
+			INNER JOIN expenses e ON p.expense_id = e.id
+			WHERE e.group_id = $1 AND e.deleted_at IS NULL AND p.user_id <> e.paid_by
+			UNION ALL
+			SELECT s.payee_id, s.payer_id, s.base_amount
+			FROM settlements s
+			WHERE s.group_id = $1 AND s.deleted_at IS NULL
+		) d
+		WHERE debtor_id IS NOT NULL AND creditor_id IS NOT NULL
+		GROUP BY debtor_id, creditor_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type pair struct{ low, high int64 }
+	net := make(map[pair]Money)
+
+	for rows.Next() {
+		var debtorID, creditorID int64
+		var amount Money
+		if err := rows.Scan(&debtorID, &creditorID, &amount); err != nil {
+			return nil, err
 		}
 
+		// Track each pair once, as what the lower user ID owes the higher one
+		if debtorID < creditorID {
+			net[pair{debtorID, creditorID}] += amount
+		} else {
+			net[pair{creditorID, debtorID}] -= amount
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	debts := []Debt{}
+
+	for p, amount := range net {
+		switch {
+		case amount > 0:
+			debts = append(debts, Debt{DebtorID: p.low, CreditorID: p.high, Amount: amount})
+		case amount < 0:
+			debts = append(debts, Debt{DebtorID: p.high, CreditorID: p.low, Amount: -amount})
+		}
 	}
-	fmt.Println(expenseMap)
 
-	// Query settlements and adjust balances
-	rows, err = m.DB.Query(settlementsQuery, groupID)
+	sort.Slice(debts, func(i, j int) bool {
+		if debts[i].DebtorID != debts[j].DebtorID {
+			return debts[i].DebtorID < debts[j].DebtorID
+		}
+		return debts[i].CreditorID < debts[j].CreditorID
+	})
+
+	return debts, nil
+}
+
+type GroupPosition struct {
+	GroupID        int64          `json:"group_id"`
+	Name           string         `json:"name"`
+	Currency       string         `json:"currency"`
+	Balance        Money          `json:"balance"`
+	Counterparties []Counterparty `json:"counterparties"`
+}
+
+type CurrencyPosition struct {
+	Currency       string         `json:"currency"`
+	Balance        Money          `json:"balance"`
+	Counterparties []Counterparty `json:"counterparties"`
+}
+
+// CalculateUserPositions returns the user's balance and counterparties in
+// every group they are an active member of. The user's side of every share
+// and settlement is netted per group and counterparty in a single query, so
+// the cost does not grow with the number of groups.
+func (m BalanceModel) CalculateUserPositions(userID int64) ([]GroupPosition, error) {
+	query := `
+		SELECT g.id, g.name, g.currency, d.counterparty_id, COALESCE(SUM(d.amount), 0)
+		FROM groups g
+		INNER JOIN group_members gm ON gm.group_id = g.id
+		LEFT JOIN (
+			SELECT e.group_id, e.paid_by AS counterparty_id, ROUND(p.amount_owed * e.exchange_rate, 2) AS amount
+			FROM expense_participants p
+			INNER JOIN expenses e ON p.expense_id = e.id
+			WHERE p.user_id = $1 AND e.paid_by <> $1 AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT e.group_id, p.user_id, -ROUND(p.amount_owed * e.exchange_rate, 2)
+			FROM expense_participants p
+			INNER JOIN expenses e ON p.expense_id = e.id
+			WHERE e.paid_by = $1 AND p.user_id <> $1 AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT s.group_id, s.payer_id, s.base_amount
+			FROM settlements s
+			WHERE s.payee_id = $1 AND s.deleted_at IS NULL
+			UNION ALL
+			SELECT s.group_id, s.payee_id, -s.base_amount
+			FROM settlements s
+			WHERE s.payer_id = $1 AND s.deleted_at IS NULL
+		) d ON d.group_id = g.id AND d.counterparty_id IS NOT NULL
+		WHERE gm.user_id = $1 AND gm.is_active = true
+		GROUP BY g.id, g.name, g.currency, d.counterparty_id
+		ORDER BY g.id ASC, d.counterparty_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	positions := []GroupPosition{}
+
 	for rows.Next() {
-		var payerID, payeeID int64
-		var settledAmount float64
-		if err := rows.Scan(&payerID, &payeeID, &settledAmount); err != nil {
+		var position GroupPosition
+		var counterpartyID *int64
+		var balance Money
+
+		err := rows.Scan(&position.GroupID, &position.Name, &position.Currency, &counterpartyID, &balance)
+		if err != nil {
 			return nil, err
 		}
 
-		// Deduct settled amount from payer's debt
-		expenseMap[payerID] -= settledAmount
-		// Add the settled amount to payee's debt (or reduce how much they are owed)
-		expenseMap[payeeID] += settledAmount
+		// Rows come ordered by group, one per counterparty
+		if len(positions) == 0 || positions[len(positions)-1].GroupID != position.GroupID {
+			position.Counterparties = []Counterparty{}
+			positions = append(positions, position)
+		}
+
+		if counterpartyID == nil || balance == 0 {
+			continue
+		}
+
+		current := &positions[len(positions)-1]
+		current.Counterparties = append(current.Counterparties, Counterparty{UserID: *counterpartyID, Balance: balance})
+		current.Balance += balance
 	}
 
-	// Convert the expenseMap to the slice of balances
-	for userID, balance := range expenseMap {
-		balances = append(balances, Balance{
-			UserID:  userID,
-			Balance: balance,
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+// SummarizePositions adds up group positions per currency, since balances in
+// groups with different base currencies cannot be added together.
+func SummarizePositions(positions []GroupPosition) []CurrencyPosition {
+	totals := make(map[string]map[int64]Money)
+	balances := make(map[string]Money)
+
+	for _, position := range positions {
+		if totals[position.Currency] == nil {
+			totals[position.Currency] = make(map[int64]Money)
+		}
+
+		balances[position.Currency] += position.Balance
+		for _, counterparty := range position.Counterparties {
+			totals[position.Currency][counterparty.UserID] += counterparty.Balance
+		}
+	}
+
+	summary := []CurrencyPosition{}
+
+	for currency, counterpartyTotals := range totals {
+		currencyPosition := CurrencyPosition{
+			Currency:       currency,
+			Balance:        balances[currency],
+			Counterparties: []Counterparty{},
+		}
+
+		for userID, balance := range counterpartyTotals {
+			if balance != 0 {
+				currencyPosition.Counterparties = append(currencyPosition.Counterparties, Counterparty{UserID: userID, Balance: balance})
+			}
+		}
+
+		sort.Slice(currencyPosition.Counterparties, func(i, j int) bool {
+			return currencyPosition.Counterparties[i].UserID < currencyPosition.Counterparties[j].UserID
 		})
+
+		summary = append(summary, currencyPosition)
 	}
 
-	return balances, nil
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Currency < summary[j].Currency
+	})
+
+	return summary
 }
@@ -0,0 +1,282 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The balance benchmarks need a migrated PostgreSQL database, for example:
+//
+//	TRICLONE_TEST_DB_DSN=postgres://... go test -run=^$ -bench=GroupBalances ./internal/data
+func openBenchmarkDB(b *testing.B) *sql.DB {
+	dsn := os.Getenv("TRICLONE_TEST_DB_DSN")
+	if dsn == "" {
+		b.Skip("TRICLONE_TEST_DB_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err = db.Ping(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// seedBenchmarkGroup creates a group where every member takes an equal share
+// of every expense and returns its ID. Everything is removed again once the
+// benchmark finishes.
+func seedBenchmarkGroup(b *testing.B, db *sql.DB, memberCount, expenses int) int64 {
+	b.Helper()
+
+	prefix := fmt.Sprintf("bench-%d-", time.Now().UnixNano())
+
+	rows, err := db.Query(`
+		INSERT INTO users (name, email, password_hash, activated)
+		SELECT 'benchmark', $1 || i || '@example.com', '\x00', true
+		FROM generate_series(1, $2) i
+		RETURNING id`, prefix, memberCount)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			b.Fatal(err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+
+	b.Cleanup(func() {
+		db.Exec(`DELETE FROM users WHERE id = ANY($1)`, pq.Array(userIDs))
+	})
+
+	var groupID int64
+	err = db.QueryRow(`
+		INSERT INTO groups (name, currency, created_by)
+		VALUES ('benchmark', 'EUR', $1)
+		RETURNING id`, userIDs[0]).Scan(&groupID)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() {
+		db.Exec(`DELETE FROM groups WHERE id = $1`, groupID)
+	})
+
+	members := pq.Array(userIDs)
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO group_members (group_id, user_id)
+		SELECT $1, unnest($2::int[])`, []any{groupID, members}},
+
+		{`INSERT INTO expenses (group_id, amount, currency, exchange_rate, base_amount, description, paid_by)
+		SELECT $1, 10.00 * cardinality($2::int[]), 'EUR', 1, 10.00 * cardinality($2::int[]), 'benchmark', ($2::int[])[1 + i % cardinality($2::int[])]
+		FROM generate_series(1, $3) i`, []any{groupID, members, expenses}},
+
+		{`INSERT INTO expense_participants (expense_id, user_id, amount_owed)
+		SELECT e.id, u.id, 10.00
+		FROM expenses e, unnest($2::int[]) u(id)
+		WHERE e.group_id = $1`, []any{groupID, members}},
+
+		{`INSERT INTO settlements (group_id, payer_id, payee_id, amount, currency, exchange_rate, base_amount)
+		SELECT $1, ($2::int[])[1 + i % cardinality($2::int[])], ($2::int[])[1 + (i + 1) % cardinality($2::int[])], 5.00, 'EUR', 1, 5.00
+		FROM generate_series(1, $3) i`, []any{groupID, members, expenses / 10}},
+	}
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement.query, statement.args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return groupID
+}
+
+// legacyCalculateGroupBalances is the previous implementation, which pulled
+// every participant row into Go and ran a second query for settlements. It is
+// kept here only as a baseline for the benchmarks.
+func legacyCalculateGroupBalances(db *sql.DB, groupID int64) ([]Balance, error) {
+	rows, err := db.Query(`
+		SELECT e.id, e.paid_by, p.user_id, SUM(ROUND(p.amount_owed * e.exchange_rate, 2)) AS total_owed
+		FROM expense_participants p
+		INNER JOIN expenses e ON p.expense_id = e.id
+		WHERE e.group_id = $1
+		GROUP BY e.id, e.paid_by, p.user_id`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenseMap := make(map[int64]Money)
+
+	for rows.Next() {
+		var expenseID, paidByUserID, participantUserID int64
+		var totalOwed Money
+		if err := rows.Scan(&expenseID, &paidByUserID, &participantUserID, &totalOwed); err != nil {
+			return nil, err
+		}
+		expenseMap[participantUserID] += totalOwed
+		expenseMap[paidByUserID] -= totalOwed
+	}
+
+	rows, err = db.Query(`
+		SELECT s.payer_id, s.payee_id, SUM(s.base_amount) AS settled_amount
+		FROM settlements s
+		WHERE s.group_id = $1
+		GROUP BY s.payer_id, s.payee_id`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payerID, payeeID int64
+		var settledAmount Money
+		if err := rows.Scan(&payerID, &payeeID, &settledAmount); err != nil {
+			return nil, err
+		}
+		expenseMap[payerID] -= settledAmount
+		expenseMap[payeeID] += settledAmount
+	}
+
+	var balances []Balance
+	for userID, balance := range expenseMap {
+		balances = append(balances, Balance{UserID: userID, Balance: balance})
+	}
+
+	return balances, nil
+}
+
+func BenchmarkCalculateGroupBalances(b *testing.B) {
+	db := openBenchmarkDB(b)
+
+	m := BalanceModel{DB: db, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for _, size := range []int{100, 1_000, 10_000} {
+		groupID := seedBenchmarkGroup(b, db, 8, size)
+
+		b.Run(fmt.Sprintf("aggregated/expenses=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := m.CalculateGroupBalances(groupID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("legacy/expenses=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := legacyCalculateGroupBalances(db, groupID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSimplifyDebts(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances []Balance
+	}{
+		{name: "no balances"},
+		{
+			name:     "settled group",
+			balances: []Balance{{UserID: 1, Balance: 0}, {UserID: 2, Balance: 0}, {UserID: 3, Balance: 0}},
+		},
+		{
+			name:     "one debtor and one creditor",
+			balances: []Balance{{UserID: 1, Balance: 1500}, {UserID: 2, Balance: -1500}},
+		},
+		{
+			name:     "one creditor paid by everyone",
+			balances: []Balance{{UserID: 1, Balance: -3000}, {UserID: 2, Balance: 1000}, {UserID: 3, Balance: 1000}, {UserID: 4, Balance: 1000}},
+		},
+		{
+			name:     "one debtor owing everyone",
+			balances: []Balance{{UserID: 1, Balance: 301}, {UserID: 2, Balance: -100}, {UserID: 3, Balance: -100}, {UserID: 4, Balance: -101}},
+		},
+		{
+			name: "uneven amounts",
+			balances: []Balance{
+				{UserID: 1, Balance: 4567}, {UserID: 2, Balance: -1234}, {UserID: 3, Balance: 789},
+				{UserID: 4, Balance: -3333}, {UserID: 5, Balance: 0}, {UserID: 6, Balance: -789},
+			},
+		},
+		{
+			name: "many members",
+			balances: []Balance{
+				{UserID: 1, Balance: 1}, {UserID: 2, Balance: 2}, {UserID: 3, Balance: 3}, {UserID: 4, Balance: 4},
+				{UserID: 5, Balance: 5}, {UserID: 6, Balance: -7}, {UserID: 7, Balance: -8},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers := SimplifyDebts(tt.balances)
+
+			remaining := make(map[int64]Money)
+			nonZero := 0
+			for _, balance := range tt.balances {
+				remaining[balance.UserID] = balance.Balance
+				if balance.Balance != 0 {
+					nonZero++
+				}
+			}
+
+			if nonZero == 0 && len(transfers) != 0 {
+				t.Errorf("got %d transfers for a settled group, want none", len(transfers))
+			}
+
+			if nonZero > 0 && len(transfers) > nonZero-1 {
+				t.Errorf("got %d transfers, want at most %d", len(transfers), nonZero-1)
+			}
+
+			for _, transfer := range transfers {
+				if transfer.From == transfer.To {
+					t.Errorf("transfer from user %d to themselves", transfer.From)
+				}
+
+				if transfer.Amount <= 0 {
+					t.Errorf("transfer from user %d to user %d of %s, want a positive amount", transfer.From, transfer.To, transfer.Amount)
+				}
+
+				remaining[transfer.From] -= transfer.Amount
+				remaining[transfer.To] += transfer.Amount
+			}
+
+			for userID, balance := range remaining {
+				if balance != 0 {
+					t.Errorf("user %d is left with a balance of %s", userID, balance)
+				}
+			}
+		})
+	}
+}
+
+func TestSimplifyDebtsSettledGroupIsEmpty(t *testing.T) {
+	transfers := SimplifyDebts([]Balance{{UserID: 1}, {UserID: 2}})
+
+	if transfers == nil || len(transfers) != 0 {
+		t.Errorf("got %#v, want an empty list", transfers)
+	}
+}
@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// Category classifies expenses. Built-in categories have no group and are
+// available to every group; they cannot be changed through the API.
+type Category struct {
+	ID        int64     `json:"id"`
+	GroupID   *int64    `json:"group_id"`
+	Name      string    `json:"name"`
+	BuiltIn   bool      `json:"built_in"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 50, "name", "must not be more than 50 bytes long")
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func (m CategoryModel) GetAllForGroup(groupID int64) ([]*Category, error) {
+	query := `
+		SELECT id, group_id, name, group_id IS NULL, created_at, updated_at
+		FROM categories
+		WHERE group_id = $1 OR group_id IS NULL
+		ORDER BY group_id IS NULL DESC, name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.GroupID,
+			&category.Name,
+			&category.BuiltIn,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// Get returns a category that is available to the group, whether it is one of
+// the group's own or a built-in one.
+func (m CategoryModel) Get(groupID, categoryID int64) (*Category, error) {
+	query := `
+		SELECT id, group_id, name, group_id IS NULL, created_at, updated_at
+		FROM categories
+		WHERE id = $1 AND (group_id = $2 OR group_id IS NULL)`
+
+	var category Category
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, categoryID, groupID).Scan(
+		&category.ID,
+		&category.GroupID,
+		&category.Name,
+		&category.BuiltIn,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+func (m CategoryModel) Insert(category *Category) error {
+	query := `
+		INSERT INTO categories (group_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, category.GroupID, category.Name).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_group_id_name_key"`:
+			return ErrDuplicateEntry
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CategoryModel) Update(category *Category) error {
+	query := `
+		UPDATE categories
+		SET name = $1, updated_at = NOW()
+		WHERE id = $2 AND group_id = $3 AND updated_at = $4
+		RETURNING updated_at`
+
+	args := []any{category.Name, category.ID, category.GroupID, category.UpdatedAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&category.UpdatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_group_id_name_key"`:
+			return ErrDuplicateEntry
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes one of the group's own categories. Expenses that used it are
+// left uncategorized.
+func (m CategoryModel) Delete(groupID, categoryID int64) error {
+	query := `
+		DELETE FROM categories
+		WHERE id = $1 AND group_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, categoryID, groupID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
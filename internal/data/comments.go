@@ -0,0 +1,182 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// Comment is a message left on either an expense or a settlement. Exactly one
+// of ExpenseID and SettlementID is set.
+type Comment struct {
+	ID           int64     `json:"id"`
+	ExpenseID    *int64    `json:"expense_id,omitempty"`
+	SettlementID *int64    `json:"settlement_id,omitempty"`
+	AuthorID     *int64    `json:"author_id"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func ValidateComment(v *validator.Validator, comment *Comment) {
+	v.Check(comment.Body != "", "body", "must be provided")
+	v.Check(len(comment.Body) <= 2000, "body", "must not be more than 2000 bytes long")
+}
+
+type CommentModel struct {
+	DB *sql.DB
+}
+
+func (m CommentModel) Insert(comment *Comment) error {
+	query := `
+		INSERT INTO comments (expense_id, settlement_id, author_id, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	args := []any{comment.ExpenseID, comment.SettlementID, comment.AuthorID, comment.Body}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+}
+
+func (m CommentModel) GetAllForExpense(expenseID int64, filters Filters) ([]*Comment, Metadata, error) {
+	return m.getAll("expense_id", expenseID, filters)
+}
+
+func (m CommentModel) GetAllForSettlement(settlementID int64, filters Filters) ([]*Comment, Metadata, error) {
+	return m.getAll("settlement_id", settlementID, filters)
+}
+
+func (m CommentModel) getAll(column string, id int64, filters Filters) ([]*Comment, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, expense_id, settlement_id, author_id, body, created_at, updated_at
+		FROM comments
+		WHERE %s = $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, column, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	comments := []*Comment{}
+
+	for rows.Next() {
+		var comment Comment
+		err := rows.Scan(
+			&totalRecords,
+			&comment.ID,
+			&comment.ExpenseID,
+			&comment.SettlementID,
+			&comment.AuthorID,
+			&comment.Body,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return comments, metadata, nil
+}
+
+func (m CommentModel) Get(commentID int64) (*Comment, error) {
+	query := `
+		SELECT id, expense_id, settlement_id, author_id, body, created_at, updated_at
+		FROM comments
+		WHERE id = $1`
+
+	var comment Comment
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, commentID).Scan(
+		&comment.ID,
+		&comment.ExpenseID,
+		&comment.SettlementID,
+		&comment.AuthorID,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &comment, nil
+}
+
+func (m CommentModel) Update(comment *Comment) error {
+	query := `
+		UPDATE comments
+		SET body = $1, updated_at = NOW()
+		WHERE id = $2 AND updated_at = $3
+		RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, comment.Body, comment.ID, comment.UpdatedAt).Scan(&comment.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CommentModel) Delete(commentID int64) error {
+	query := `
+		DELETE FROM comments
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, commentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
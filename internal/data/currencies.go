@@ -0,0 +1,135 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+const DefaultCurrency = "EUR"
+
+var currencyCodes = []string{
+	"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN",
+	"BAM", "BBD", "BDT", "BGN", "BHD", "BIF", "BMD", "BND", "BOB", "BRL",
+	"BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHF", "CLP", "CNY",
+	"COP", "CRC", "CUP", "CVE", "CZK", "DJF", "DKK", "DOP", "DZD", "EGP",
+	"ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP", "GMD",
+	"GNF", "GTQ", "GYD", "HKD", "HNL", "HTG", "HUF", "IDR", "ILS", "INR",
+	"IQD", "IRR", "ISK", "JMD", "JOD", "JPY", "KES", "KGS", "KHR", "KMF",
+	"KPW", "KRW", "KWD", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL",
+	"LYD", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR",
+	"MVR", "MWK", "MXN", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR",
+	"NZD", "OMR", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "PYG", "QAR",
+	"RON", "RSD", "RUB", "RWF", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD",
+	"SHP", "SLE", "SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB",
+	"TJS", "TMT", "TND", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH", "UGX",
+	"USD", "UYU", "UZS", "VES", "VND", "VUV", "WST", "XAF", "XCD", "XOF",
+	"XPF", "YER", "ZAR", "ZMW", "ZWG",
+}
+
+func ValidateCurrency(v *validator.Validator, key, currency string) {
+	v.Check(currency != "", key, "must be provided")
+	v.Check(validator.PermittedValue(currency, currencyCodes...), key, "must be a valid ISO 4217 currency code")
+}
+
+// Rate is an exchange rate stored as a fixed-point number with eight decimal
+// places, matching the NUMERIC(18, 8) columns in the database.
+type Rate int64
+
+const (
+	rateScale       = 100_000_000
+	RateOne    Rate = rateScale
+	MaxRate    Rate = 9_999_999_999_99999999
+	ratePlaces      = 8
+)
+
+var ErrInvalidRateFormat = errors.New("invalid exchange rate: must be a number with at most eight decimal places")
+
+func ParseRate(s string) (Rate, error) {
+	value, ok := parseFixed(s, ratePlaces, 10)
+	if !ok {
+		return 0, ErrInvalidRateFormat
+	}
+
+	return Rate(value), nil
+}
+
+func (r Rate) String() string {
+	return formatFixed(int64(r), ratePlaces)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(jsonValue []byte) error {
+	if string(jsonValue) == "null" {
+		return nil
+	}
+
+	value, err := ParseRate(strings.Trim(string(jsonValue), `"`))
+	if err != nil {
+		return err
+	}
+
+	*r = value
+
+	return nil
+}
+
+func (r *Rate) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		value, err := ParseRate(string(v))
+		if err != nil {
+			return err
+		}
+		*r = value
+	case string:
+		value, err := ParseRate(v)
+		if err != nil {
+			return err
+		}
+		*r = value
+	case int64:
+		*r = Rate(v * rateScale)
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", src)
+	}
+
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Convert applies the exchange rate to the amount, rounding half away from
+// zero to the nearest cent exactly like ROUND(numeric, 2) does in PostgreSQL.
+func (m Money) Convert(rate Rate) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(rate)))
+
+	half := big.NewInt(rateScale / 2)
+	if product.Sign() < 0 {
+		product.Sub(product, half)
+	} else {
+		product.Add(product, half)
+	}
+
+	product.Quo(product, big.NewInt(rateScale))
+
+	// Out of range results are clamped so that validation rejects them.
+	if !product.IsInt64() {
+		if product.Sign() < 0 {
+			return Money(math.MinInt64)
+		}
+		return Money(math.MaxInt64)
+	}
+
+	return Money(product.Int64())
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrInvalidDateFormat = errors.New("invalid date: must be in YYYY-MM-DD format")
+
+// Date is a calendar day with no time of day or time zone, such as the day an
+// expense was incurred on.
+type Date struct {
+	time.Time
+}
+
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, ErrInvalidDateFormat
+	}
+
+	return Date{t}, nil
+}
+
+// Today returns the current day in UTC.
+func Today() Date {
+	now := time.Now().UTC()
+	return Date{time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d *Date) UnmarshalJSON(jsonValue []byte) error {
+	if string(jsonValue) == "null" {
+		return nil
+	}
+
+	date, err := ParseDate(strings.Trim(string(jsonValue), `"`))
+	if err != nil {
+		return err
+	}
+
+	*d = date
+
+	return nil
+}
+
+func (d *Date) Scan(src any) error {
+	switch src := src.(type) {
+	case time.Time:
+		*d = Date{time.Date(src.Year(), src.Month(), src.Day(), 0, 0, 0, 0, time.UTC)}
+		return nil
+	case []byte:
+		return d.scanString(string(src))
+	case string:
+		return d.scanString(src)
+	default:
+		return fmt.Errorf("cannot scan %T into Date", src)
+	}
+}
+
+func (d *Date) scanString(s string) error {
+	date, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+
+	*d = date
+
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
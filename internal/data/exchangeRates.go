@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+type ExchangeRate struct {
+	ID        int64     `json:"id"`
+	GroupID   int64     `json:"group_id"`
+	Currency  string    `json:"currency"`
+	Rate      Rate      `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateExchangeRate(v *validator.Validator, exchangeRate *ExchangeRate, baseCurrency string) {
+	v.Check(exchangeRate.GroupID > 0, "group_id", "must be positive")
+
+	ValidateCurrency(v, "currency", exchangeRate.Currency)
+	v.Check(exchangeRate.Currency != baseCurrency, "currency", "must be different from the group's base currency")
+
+	v.Check(exchangeRate.Rate > 0, "rate", "must be positive")
+	v.Check(exchangeRate.Rate <= MaxRate, "rate", "must not be more than "+MaxRate.String())
+}
+
+type ExchangeRateModel struct {
+	DB *sql.DB
+}
+
+func (m ExchangeRateModel) GetAllForGroup(groupID int64) ([]*ExchangeRate, error) {
+	query := `
+		SELECT id, group_id, currency, rate, updated_at
+		FROM exchange_rates
+		WHERE group_id = $1
+		ORDER BY currency ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exchangeRates := []*ExchangeRate{}
+
+	for rows.Next() {
+		var exchangeRate ExchangeRate
+		err := rows.Scan(
+			&exchangeRate.ID,
+			&exchangeRate.GroupID,
+			&exchangeRate.Currency,
+			&exchangeRate.Rate,
+			&exchangeRate.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exchangeRates = append(exchangeRates, &exchangeRate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exchangeRates, nil
+}
+
+func (m ExchangeRateModel) Get(groupID int64, currency string) (*ExchangeRate, error) {
+	query := `
+		SELECT id, group_id, currency, rate, updated_at
+		FROM exchange_rates
+		WHERE group_id = $1 AND currency = $2`
+
+	var exchangeRate ExchangeRate
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, groupID, currency).Scan(
+		&exchangeRate.ID,
+		&exchangeRate.GroupID,
+		&exchangeRate.Currency,
+		&exchangeRate.Rate,
+		&exchangeRate.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &exchangeRate, nil
+}
+
+func (m ExchangeRateModel) Upsert(exchangeRate *ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (group_id, currency, rate)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (group_id, currency)
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING id, updated_at`
+
+	args := []any{exchangeRate.GroupID, exchangeRate.Currency, exchangeRate.Rate}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&exchangeRate.ID, &exchangeRate.UpdatedAt)
+}
+
+func (m ExchangeRateModel) Delete(groupID int64, currency string) error {
+	query := `
+		DELETE FROM exchange_rates
+		WHERE group_id = $1 AND currency = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, groupID, currency)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
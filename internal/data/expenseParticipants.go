@@ -14,7 +14,7 @@ type ExpenseParticipant struct {
 	ID         int64     `json:"id"`
 	ExpenseID  int64     `json:"expense_id"`
 	UserID     int64     `json:"user_id"`
-	AmountOwed float64   `json:"amount_owed"`
+	AmountOwed Money     `json:"amount_owed"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
@@ -26,13 +26,14 @@ func ValidateParticipant(v *validator.Validator, participant *ExpenseParticipant
 	v.Check(participant.ExpenseID > 0, "expense_id", "must be non negative")
 	v.Check(participant.UserID > 0, "user_id", "must be non negative")
 	v.Check(participant.AmountOwed > 0, "amount_owed", "must be non negative")
+	v.Check(participant.AmountOwed <= MaxMoney, "amount_owed", "must not be more than "+MaxMoney.String())
 }
 
 func (m ExpenseParticipantModel) GetAllForGroupAndExpense(groupID, expenseID int64, filters Filters) ([]*ExpenseParticipant, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), id, expense_id, user_id, amount_owed, updated_at
 		FROM expense_participants
-		WHERE expense_id = $1 AND expense_id IN (SELECT id FROM expenses WHERE group_id = $2)
+		WHERE expense_id = $1 AND expense_id IN (SELECT id FROM expenses WHERE group_id = $2 AND deleted_at IS NULL)
 		ORDER BY %s %s
 		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
@@ -73,7 +74,16 @@ func (m ExpenseParticipantModel) GetAllForGroupAndExpense(groupID, expenseID int
 	return participants, metadata, nil
 }
 
-func (m ExpenseParticipantModel) Insert(participant *ExpenseParticipant) error {
+func (m ExpenseParticipantModel) Insert(participant *ExpenseParticipant, actorID *int64) error {
+	return m.InsertAll(participant.ExpenseID, []*ExpenseParticipant{participant}, actorID)
+}
+
+// InsertTx adds the participant as part of tx without auditing it.
+func (m ExpenseParticipantModel) InsertTx(tx *sql.Tx, participant *ExpenseParticipant) error {
+	return m.insert(tx, participant)
+}
+
+func (m ExpenseParticipantModel) insert(q queryer, participant *ExpenseParticipant) error {
 	query := `
 		INSERT INTO expense_participants(expense_id, user_id, amount_owed)
 		VALUES ($1, $2, $3)
@@ -83,7 +93,7 @@ func (m ExpenseParticipantModel) Insert(participant *ExpenseParticipant) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&participant.ID, &participant.UpdatedAt)
+	err := q.QueryRowContext(ctx, query, args...).Scan(&participant.ID, &participant.UpdatedAt)
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "expense_participants_expense_id_user_id_key"`:
@@ -96,6 +106,48 @@ func (m ExpenseParticipantModel) Insert(participant *ExpenseParticipant) error {
 	return nil
 }
 
+// InsertAll adds a batch of participants to an expense in a single
+// transaction. The expense is locked while its shares are checked, and a
+// *ShareError is returned if they would no longer fit its amount.
+func (m ExpenseParticipantModel) InsertAll(expenseID int64, participants []*ExpenseParticipant, actorID *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockExpense(ctx, tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	for _, participant := range participants {
+		participant.ExpenseID = expenseID
+
+		err = audited(ctx, tx, actorID, AuditEntityParticipant, &participant.ID, func() error {
+			return m.InsertTx(tx, participant)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = checkExpenseShares(ctx, tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (m ExpenseParticipantModel) Get(participantID int64) (*ExpenseParticipant, error) {
 	query := `
 		SELECT id, expense_id, user_id, amount_owed, updated_at
@@ -125,19 +177,34 @@ func (m ExpenseParticipantModel) Get(participantID int64) (*ExpenseParticipant,
 	return &participant, nil
 }
 
-func (m ExpenseParticipantModel) Update(participant *ExpenseParticipant) error {
+// Update changes the participant's share. Like InsertAll, it returns a
+// *ShareError if the expense's shares would no longer fit its amount.
+func (m ExpenseParticipantModel) Update(participant *ExpenseParticipant, actorID *int64) error {
 	query := `
 	UPDATE expense_participants
 	SET amount_owed = $1, updated_at = NOW()
-	WHERE id = $2
+	WHERE id = $2 AND expense_id = $3
 	RETURNING updated_at`
 
-	args := []any{participant.AmountOwed, participant.ID}
+	args := []any{participant.AmountOwed, participant.ID, participant.ExpenseID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&participant.UpdatedAt)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockExpense(ctx, tx, participant.ExpenseID)
+	if err != nil {
+		return err
+	}
+
+	err = audited(ctx, tx, actorID, AuditEntityParticipant, &participant.ID, func() error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&participant.UpdatedAt)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -146,29 +213,70 @@ func (m ExpenseParticipantModel) Update(participant *ExpenseParticipant) error {
 			return err
 		}
 	}
-	return nil
+
+	err = checkExpenseShares(ctx, tx, participant.ExpenseID)
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, participant.ExpenseID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m ExpenseParticipantModel) Delete(participantID int64) error {
+// Delete removes the participant from the expense. Like InsertAll, it returns
+// a *ShareError if the remaining shares would no longer fit the amount.
+func (m ExpenseParticipantModel) Delete(expenseID, participantID int64, actorID *int64) error {
 	query := `
 		DELETE FROM expense_participants
-		WHERE id = $1`
+		WHERE id = $1 AND expense_id = $2`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, participantID)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = lockExpense(ctx, tx, expenseID)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+
+	err = audited(ctx, tx, actorID, AuditEntityParticipant, &participantID, func() error {
+		result, err := tx.ExecContext(ctx, query, participantID, expenseID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	err = checkExpenseShares(ctx, tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
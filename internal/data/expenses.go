@@ -5,51 +5,198 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/manuelam2003/triclone/internal/storage"
 	"github.com/manuelam2003/triclone/internal/validator"
 )
 
 type Expense struct {
-	ID          int64     `json:"id"`
-	GroupID     int64     `json:"group_id"`
-	Amount      float64   `json:"amount"`
-	Description string    `json:"description"`
-	PaidBy      *int64    `json:"paid_by"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 int64      `json:"id"`
+	GroupID            int64      `json:"group_id"`
+	Amount             Money      `json:"amount"`
+	Currency           string     `json:"currency"`
+	ExchangeRate       Rate       `json:"exchange_rate"`
+	BaseAmount         Money      `json:"base_amount"`
+	Description        string     `json:"description"`
+	PaidBy             *int64     `json:"paid_by"`
+	CategoryID         *int64     `json:"category_id"`
+	IncurredOn         Date       `json:"incurred_on"`
+	Incomplete         bool       `json:"incomplete"`
+	RecurringExpenseID *int64     `json:"recurring_expense_id,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy          *int64     `json:"deleted_by,omitempty"`
+}
+
+// ValidateExpenseShares enforces that the participants' shares add up to the
+// expense amount. Incomplete expenses may fall short but never exceed it.
+func ValidateExpenseShares(v *validator.Validator, expense *Expense, shares Money) {
+	switch {
+	case shares > expense.Amount:
+		v.AddError("amount", fmt.Sprintf("participants' shares exceed the expense amount by %s", shares-expense.Amount))
+	case shares < expense.Amount && !expense.Incomplete:
+		v.AddError("amount", fmt.Sprintf("participants' shares fall short of the expense amount by %s, add the missing shares or flag the expense as incomplete", expense.Amount-shares))
+	}
+}
+
+// ShareError is returned when a change would leave an expense's shares in a
+// state that ValidateExpenseShares rejects. The change is rolled back.
+type ShareError struct {
+	Message string
+}
+
+func (e *ShareError) Error() string {
+	return e.Message
+}
+
+// lockExpense locks the expense's row until tx ends, so that concurrent
+// changes to its shares are checked one after another.
+func lockExpense(ctx context.Context, tx *sql.Tx, expenseID int64) error {
+	query := `
+		SELECT id
+		FROM expenses
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`
+
+	err := tx.QueryRowContext(ctx, query, expenseID).Scan(&expenseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkExpenseShares sums the expense's shares as they stand within tx and
+// returns a *ShareError if they no longer fit the expense amount. The expense
+// must have been locked or updated in tx beforehand.
+func checkExpenseShares(ctx context.Context, tx *sql.Tx, expenseID int64) error {
+	query := `
+		SELECT e.amount, e.incomplete, COALESCE(SUM(p.amount_owed), 0)
+		FROM expenses e
+		LEFT JOIN expense_participants p ON p.expense_id = e.id
+		WHERE e.id = $1
+		GROUP BY e.id`
+
+	var expense Expense
+	var shares Money
+
+	err := tx.QueryRowContext(ctx, query, expenseID).Scan(&expense.Amount, &expense.Incomplete, &shares)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+
+	if ValidateExpenseShares(v, &expense, shares); !v.Valid() {
+		return &ShareError{Message: v.Errors["amount"]}
+	}
+
+	return nil
 }
 
 type ExpenseModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Storage storage.Storage
+	Logger  *slog.Logger
 }
 
 func ValidateExpense(v *validator.Validator, expense *Expense) {
 	v.Check(expense.GroupID > 0, "group_id", "must be non negative")
-	v.Check(expense.Amount > 0.0, "amount", "must be non negative")
+	v.Check(expense.Amount > 0, "amount", "must be non negative")
+	v.Check(expense.Amount <= MaxMoney, "amount", "must not be more than "+MaxMoney.String())
+	v.Check(expense.BaseAmount <= MaxMoney, "amount", "must not be more than "+MaxMoney.String()+" once converted to the group's currency")
+
+	ValidateCurrency(v, "currency", expense.Currency)
+	v.Check(expense.ExchangeRate > 0, "exchange_rate", "must be positive")
 	v.Check(expense.Description != "", "description", "must be provided")
 	v.Check(len(expense.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(!expense.IncurredOn.IsZero(), "incurred_on", "must be provided")
+}
+
+func (m ExpenseModel) Insert(expense *Expense, actorID *int64) error {
+	return m.InsertWithParticipants(expense, nil, actorID)
+}
+
+// InsertTx adds the expense as part of tx. It is not audited on its own, as
+// the audit entry should also capture the participants added alongside it.
+func (m ExpenseModel) InsertTx(tx *sql.Tx, expense *Expense) error {
+	return m.insert(tx, expense)
 }
 
-func (m ExpenseModel) Insert(expense *Expense) error {
+func (m ExpenseModel) insert(q queryer, expense *Expense) error {
 	query := `
-		INSERT INTO expenses(group_id, amount, description, paid_by)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO expenses(group_id, amount, currency, exchange_rate, base_amount, description, paid_by, category_id, incurred_on, incomplete, recurring_expense_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at`
 
-	args := []any{expense.GroupID, expense.Amount, expense.Description, *expense.PaidBy}
+	args := []any{expense.GroupID, expense.Amount, expense.Currency, expense.ExchangeRate, expense.BaseAmount, expense.Description, *expense.PaidBy, expense.CategoryID, expense.IncurredOn, expense.Incomplete, expense.RecurringExpenseID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&expense.ID, &expense.CreatedAt, &expense.UpdatedAt)
+	return q.QueryRowContext(ctx, query, args...).Scan(&expense.ID, &expense.CreatedAt, &expense.UpdatedAt)
+}
+
+// InsertWithParticipants creates the expense and all of its participants in a
+// single transaction, so either every row is written or none is.
+func (m ExpenseModel) InsertWithParticipants(expense *Expense, participants []*ExpenseParticipant, actorID *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = audited(ctx, tx, actorID, AuditEntityExpense, &expense.ID, func() error {
+		return m.insertWithParticipants(tx, expense, participants)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expense.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ExpenseModel) insertWithParticipants(tx *sql.Tx, expense *Expense, participants []*ExpenseParticipant) error {
+	err := m.InsertTx(tx, expense)
+	if err != nil {
+		return err
+	}
+
+	participantModel := ExpenseParticipantModel{DB: m.DB}
+
+	for _, participant := range participants {
+		participant.ExpenseID = expense.ID
+
+		err = participantModel.InsertTx(tx, participant)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (m ExpenseModel) Get(groupID, expenseID int64) (*Expense, error) {
 	query := `
-		SELECT id, group_id, amount, description, paid_by, created_at, updated_at
+		SELECT id, group_id, amount, currency, exchange_rate, base_amount, description, paid_by, category_id, incurred_on, incomplete, recurring_expense_id, created_at, updated_at
 		FROM expenses
-		WHERE id = $1 AND group_id = $2`
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -60,8 +207,15 @@ func (m ExpenseModel) Get(groupID, expenseID int64) (*Expense, error) {
 		&expense.ID,
 		&expense.GroupID,
 		&expense.Amount,
+		&expense.Currency,
+		&expense.ExchangeRate,
+		&expense.BaseAmount,
 		&expense.Description,
 		&expense.PaidBy,
+		&expense.CategoryID,
+		&expense.IncurredOn,
+		&expense.Incomplete,
+		&expense.RecurringExpenseID,
 		&expense.CreatedAt,
 		&expense.UpdatedAt,
 	)
@@ -76,20 +230,23 @@ func (m ExpenseModel) Get(groupID, expenseID int64) (*Expense, error) {
 	return &expense, nil
 }
 
-func (m ExpenseModel) GetAll(groupID int64, description string, paidBy int64, filters Filters) ([]*Expense, Metadata, error) {
+func (m ExpenseModel) GetAll(groupID int64, description string, paidBy, categoryID int64, from, to *Date, filters Filters) ([]*Expense, Metadata, error) {
 	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, group_id, amount, description, paid_by, created_at, updated_at
+	SELECT count(*) OVER(), id, group_id, amount, currency, exchange_rate, base_amount, description, paid_by, category_id, incurred_on, incomplete, recurring_expense_id, created_at, updated_at
 	FROM expenses
-	WHERE group_id = $1
+	WHERE group_id = $1 AND deleted_at IS NULL
 	AND (to_tsvector('simple', description) @@ plainto_tsquery('simple', $2) OR $2 = '')
 	AND (paid_by = $3 OR $3 = 0)
+	AND (category_id = $4 OR $4 = 0)
+	AND ($5::date IS NULL OR incurred_on >= $5)
+	AND ($6::date IS NULL OR incurred_on <= $6)
 	ORDER BY %s %s, id ASC
-	LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+	LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{groupID, description, paidBy, filters.limit(), filters.offset()}
+	args := []any{groupID, description, paidBy, categoryID, from, to, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -109,8 +266,15 @@ func (m ExpenseModel) GetAll(groupID int64, description string, paidBy int64, fi
 			&expense.ID,
 			&expense.GroupID,
 			&expense.Amount,
+			&expense.Currency,
+			&expense.ExchangeRate,
+			&expense.BaseAmount,
 			&expense.Description,
 			&expense.PaidBy,
+			&expense.CategoryID,
+			&expense.IncurredOn,
+			&expense.Incomplete,
+			&expense.RecurringExpenseID,
 			&expense.CreatedAt,
 			&expense.UpdatedAt,
 		)
@@ -131,20 +295,27 @@ func (m ExpenseModel) GetAll(groupID int64, description string, paidBy int64, fi
 	return expenses, metadata, nil
 }
 
-func (m ExpenseModel) Update(expense *Expense) error {
+func (m ExpenseModel) Update(expense *Expense, actorID *int64) error {
 	query := `
 		UPDATE expenses
-		SET amount = $1, description = $2, updated_at = NOW()
-		WHERE id = $3 AND updated_at = $4
+		SET amount = $1, base_amount = $2, description = $3, category_id = $4, incurred_on = $5, incomplete = $6, updated_at = NOW()
+		WHERE id = $7 AND updated_at = $8 AND deleted_at IS NULL
 		RETURNING updated_at`
 
-	args := []any{expense.Amount, expense.Description, expense.ID, expense.UpdatedAt}
+	args := []any{expense.Amount, expense.BaseAmount, expense.Description, expense.CategoryID, expense.IncurredOn, expense.Incomplete, expense.ID, expense.UpdatedAt}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&expense.UpdatedAt)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	err = audited(ctx, tx, actorID, AuditEntityExpense, &expense.ID, func() error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&expense.UpdatedAt)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -154,39 +325,198 @@ func (m ExpenseModel) Update(expense *Expense) error {
 		}
 	}
 
-	return nil
+	// The new amount or incomplete flag must still fit the shares.
+	err = checkExpenseShares(ctx, tx, expense.ID)
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expense.ID)
+	if err != nil {
+		return err
+	}
 
+	return tx.Commit()
 }
 
-func (m ExpenseModel) Delete(groupID, expenseID int64) error {
+// SoftDelete moves the expense to the group's trash. Its participants and
+// attachments are kept so that it can be restored.
+func (m ExpenseModel) SoftDelete(groupID, expenseID int64, actorID *int64) error {
 	query := `
-		DELETE FROM expenses 
-		WHERE id = $1 AND group_id = $2`
+		UPDATE expenses
+		SET deleted_at = NOW(), deleted_by = $1, updated_at = NOW()
+		WHERE id = $2 AND group_id = $3 AND deleted_at IS NULL`
+
+	return m.change(query, expenseID, actorID, actorID, expenseID, groupID)
+}
+
+// Restore takes the expense back out of the trash.
+func (m ExpenseModel) Restore(groupID, expenseID int64, actorID *int64) error {
+	query := `
+		UPDATE expenses
+		SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW()
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NOT NULL`
+
+	return m.change(query, expenseID, actorID, expenseID, groupID)
+}
+
+// change runs a statement that affects a single expense, auditing it, syncing
+// its ledger entries and reporting ErrRecordNotFound if no row matched.
+func (m ExpenseModel) change(query string, expenseID int64, actorID *int64, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = audited(ctx, tx, actorID, AuditEntityExpense, &expenseID, func() error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expenseID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllDeleted lists the expenses in the group's trash.
+func (m ExpenseModel) GetAllDeleted(groupID int64, filters Filters) ([]*Expense, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, group_id, amount, currency, exchange_rate, base_amount, description, paid_by, category_id, incurred_on, incomplete, recurring_expense_id, created_at, updated_at, deleted_at, deleted_by
+	FROM expenses
+	WHERE group_id = $1 AND deleted_at IS NOT NULL
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	expenses := []*Expense{}
+
+	for rows.Next() {
+		var expense Expense
+
+		err := rows.Scan(
+			&totalRecords,
+			&expense.ID,
+			&expense.GroupID,
+			&expense.Amount,
+			&expense.Currency,
+			&expense.ExchangeRate,
+			&expense.BaseAmount,
+			&expense.Description,
+			&expense.PaidBy,
+			&expense.CategoryID,
+			&expense.IncurredOn,
+			&expense.Incomplete,
+			&expense.RecurringExpenseID,
+			&expense.CreatedAt,
+			&expense.UpdatedAt,
+			&expense.DeletedAt,
+			&expense.DeletedBy,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		expenses = append(expenses, &expense)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return expenses, metadata, nil
+}
+
+// Purge permanently removes an expense from the trash together with its
+// participants and attachments, and then deletes the attachments' files from
+// storage.
+func (m ExpenseModel) Purge(groupID, expenseID int64, actorID *int64) error {
+	query := `
+		DELETE FROM expenses
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NOT NULL`
 
 	args := []any{expenseID, groupID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, args...)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	keys, err := storageKeys(ctx, tx, `e.id = $1 AND e.group_id = $2 AND e.deleted_at IS NOT NULL`, args...)
+	if err != nil {
+		return err
+	}
+
+	err = audited(ctx, tx, actorID, AuditEntityExpense, &expenseID, func() error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = tx.Commit()
+	if err != nil {
+		return err
 	}
 
+	removeFiles(m.Storage, m.Logger, keys)
+
 	return nil
 }
 
 func (m ExpenseModel) CheckExpenseBelongsToGroup(expenseID, groupID int64) (bool, error) {
-	query := "SELECT EXISTS(SELECT 1 FROM expenses WHERE id = $1 AND group_id = $2)"
+	query := "SELECT EXISTS(SELECT 1 FROM expenses WHERE id = $1 AND group_id = $2 AND deleted_at IS NULL)"
 
 	var exists bool
 
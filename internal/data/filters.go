@@ -69,3 +69,21 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// CursorFilters pages through a list from newest to oldest. Cursor is the ID
+// of the last record on the previous page, or zero for the first page.
+type CursorFilters struct {
+	Cursor   int64
+	PageSize int
+}
+
+func ValidateCursorFilters(v *validator.Validator, f CursorFilters) {
+	v.Check(f.Cursor >= 0, "cursor", "must not be negative")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
+type CursorMetadata struct {
+	PageSize   int   `json:"page_size"`
+	NextCursor int64 `json:"next_cursor,omitempty"`
+}
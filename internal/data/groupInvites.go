@@ -0,0 +1,303 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+// GroupInvite lets whoever holds its token join a group. An invite with an
+// email can only be redeemed by the user with that address, who also joins
+// automatically when they activate their account. Token is only filled in
+// when the invite is created.
+type GroupInvite struct {
+	ID        int64      `json:"id"`
+	GroupID   int64      `json:"group_id"`
+	Token     string     `json:"token,omitempty"`
+	CreatedBy *int64     `json:"created_by"`
+	Email     *string    `json:"email,omitempty"`
+	Expiry    *time.Time `json:"expiry"`
+	MaxUses   *int       `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func ValidateGroupInvite(v *validator.Validator, invite *GroupInvite) {
+	if invite.Email != nil {
+		ValidateEmail(v, *invite.Email)
+	}
+
+	if invite.Expiry != nil {
+		v.Check(invite.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+
+	if invite.MaxUses != nil {
+		v.Check(*invite.MaxUses > 0, "max_uses", "must be greater than zero")
+	}
+}
+
+// redeemableInvite matches invites that have neither expired nor been used up.
+const redeemableInvite = `(expiry IS NULL OR expiry > NOW()) AND (max_uses IS NULL OR uses < max_uses)`
+
+type GroupInviteModel struct {
+	DB *sql.DB
+}
+
+func (m GroupInviteModel) Insert(invite *GroupInvite) error {
+	query := `
+		INSERT INTO group_invites (group_id, hash, created_by, email, expiry, max_uses)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	plaintext, hash, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	args := []any{invite.GroupID, hash, invite.CreatedBy, invite.Email, invite.Expiry, invite.MaxUses}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	invite.Token = plaintext
+
+	return nil
+}
+
+func (m GroupInviteModel) GetAllForGroup(groupID int64, filters Filters) ([]*GroupInvite, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, group_id, created_by, email, expiry, max_uses, uses, created_at
+		FROM group_invites
+		WHERE group_id = $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	invites := []*GroupInvite{}
+
+	for rows.Next() {
+		var invite GroupInvite
+
+		err := rows.Scan(
+			&totalRecords,
+			&invite.ID,
+			&invite.GroupID,
+			&invite.CreatedBy,
+			&invite.Email,
+			&invite.Expiry,
+			&invite.MaxUses,
+			&invite.Uses,
+			&invite.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		invites = append(invites, &invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return invites, metadata, nil
+}
+
+func (m GroupInviteModel) Delete(groupID, inviteID int64) error {
+	query := `
+		DELETE FROM group_invites
+		WHERE id = $1 AND group_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, inviteID, groupID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Redeem adds user to the group the invite is for and returns the invite.
+// Invites that do not exist, have expired, have been used up or are meant for
+// somebody else are all reported as ErrRecordNotFound, and ErrDuplicateEntry
+// means the user is already an active member, in which case the invite is
+// not used up.
+func (m GroupInviteModel) Redeem(tokenPlaintext string, user *User) (*GroupInvite, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invite, err := redeemInvite(ctx, tx, `hash = $1`, hash[:], user)
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, tx.Commit()
+}
+
+// ClaimForUser redeems every invite sent to user's email address and returns
+// the ones that added them to a group. Invites for groups they are already in
+// are left alone.
+func (m GroupInviteModel) ClaimForUser(user *User) ([]*GroupInvite, error) {
+	query := `
+		SELECT id
+		FROM group_invites
+		WHERE lower(email) = lower($1) AND ` + redeemableInvite
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	invites := []*GroupInvite{}
+
+	for _, id := range ids {
+		// Each invite gets a savepoint, so one the user cannot use does not
+		// undo the others.
+		_, err = tx.ExecContext(ctx, `SAVEPOINT claim_invite`)
+		if err != nil {
+			return nil, err
+		}
+
+		invite, err := redeemInvite(ctx, tx, `id = $1`, id, user)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrRecordNotFound), errors.Is(err, ErrDuplicateEntry):
+				_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT claim_invite`)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			default:
+				return nil, err
+			}
+		}
+
+		invites = append(invites, invite)
+	}
+
+	return invites, tx.Commit()
+}
+
+// redeemInvite uses up one redemption of the invite matched by where and adds
+// user to its group, reinstating them as a plain member if they had left.
+func redeemInvite(ctx context.Context, tx *sql.Tx, where string, arg any, user *User) (*GroupInvite, error) {
+	inviteQuery := `
+		UPDATE group_invites
+		SET uses = uses + 1
+		WHERE ` + where + ` AND ` + redeemableInvite + `
+		AND (email IS NULL OR lower(email) = lower($2))
+		RETURNING id, group_id, created_by, email, expiry, max_uses, uses, created_at`
+
+	memberQuery := `
+		INSERT INTO group_members (group_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id, user_id) DO UPDATE
+		SET is_active = TRUE, left_at = NULL, role = 'member'
+		WHERE group_members.is_active = FALSE
+		RETURNING id`
+
+	var invite GroupInvite
+
+	err := tx.QueryRowContext(ctx, inviteQuery, arg, user.Email).Scan(
+		&invite.ID,
+		&invite.GroupID,
+		&invite.CreatedBy,
+		&invite.Email,
+		&invite.Expiry,
+		&invite.MaxUses,
+		&invite.Uses,
+		&invite.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	id, err := memberID(ctx, tx, invite.GroupID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing is returned when the user is already an active member.
+	err = audited(ctx, tx, &user.ID, AuditEntityMember, &id, func() error {
+		err := tx.QueryRowContext(ctx, memberQuery, invite.GroupID, user.ID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDuplicateEntry
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
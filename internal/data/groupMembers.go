@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/manuelam2003/triclone/internal/validator"
@@ -15,6 +16,7 @@ type GroupMember struct {
 	JoinedAt time.Time  `json:"joined_at"`
 	IsActive bool       `json:"is_active"`
 	LeftAt   *time.Time `json:"left_at"`
+	Role     string     `json:"role"`
 }
 
 func ValidateGroupMember(v *validator.Validator, groupMember *GroupMember) {
@@ -26,16 +28,26 @@ type GroupMemberModel struct {
 	DB *sql.DB
 }
 
-func (m GroupMemberModel) Insert(groupID, userID int64) error {
+func (m GroupMemberModel) Insert(groupID, userID int64, actorID *int64) error {
 	query := `
 		INSERT INTO group_members (group_id, user_id)
-		VALUES ($1, $2)`
+		VALUES ($1, $2)
+		RETURNING id`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, groupID, userID)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
 
+	err = audited(ctx, tx, actorID, AuditEntityMember, &id, func() error {
+		return tx.QueryRowContext(ctx, query, groupID, userID).Scan(&id)
+	})
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "group_members_group_id_user_id_key"`:
@@ -46,32 +58,157 @@ func (m GroupMemberModel) Insert(groupID, userID int64) error {
 			return err
 		}
 	}
-	return nil
+
+	return tx.Commit()
+}
+
+func (m GroupMemberModel) Get(groupID, userID int64) (*GroupMember, error) {
+	query := `
+		SELECT id, group_id, user_id, joined_at, is_active, left_at, role
+		FROM group_members
+		WHERE group_id = $1 AND user_id = $2`
+
+	var member GroupMember
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, groupID, userID).Scan(
+		&member.ID,
+		&member.GroupID,
+		&member.UserID,
+		&member.JoinedAt,
+		&member.IsActive,
+		&member.LeftAt,
+		&member.Role,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &member, nil
+}
+
+// UpdateRole changes an active member's role. Ownership can only change hands
+// through TransferOwnership.
+func (m GroupMemberModel) UpdateRole(member *GroupMember, actorID *int64) error {
+	query := `
+		UPDATE group_members
+		SET role = $1
+		WHERE group_id = $2 AND user_id = $3 AND is_active = TRUE AND role <> 'owner'`
+
+	return m.change(query, member.GroupID, member.UserID, actorID, member.Role, member.GroupID, member.UserID)
+}
+
+// TransferOwnership makes another active member the group's owner. The
+// previous owner stays on as an admin, and is recorded as the one who made
+// both changes.
+func (m GroupMemberModel) TransferOwnership(groupID, fromUserID, toUserID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []struct {
+		query  string
+		userID int64
+	}{
+		{`UPDATE group_members SET role = 'admin' WHERE group_id = $1 AND user_id = $2 AND role = 'owner'`, fromUserID},
+		{`UPDATE group_members SET role = 'owner' WHERE group_id = $1 AND user_id = $2 AND is_active = TRUE`, toUserID},
+	}
+
+	for _, q := range queries {
+		err = changeMember(ctx, tx, groupID, q.userID, &fromUserID, q.query, groupID, q.userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (m GroupMemberModel) SoftDelete(groupID, userID int64) error {
+func (m GroupMemberModel) SoftDelete(groupID, userID int64, actorID *int64) error {
 	query := `
 		UPDATE group_members
 		SET is_active = FALSE, left_at = $1
 		WHERE group_id = $2 AND user_id = $3 AND is_active = TRUE`
 
-	leftAt := time.Now()
+	return m.change(query, groupID, userID, actorID, time.Now(), groupID, userID)
+}
+
+// change runs a statement that affects a single membership in its own
+// transaction.
+func (m GroupMemberModel) change(query string, groupID, userID int64, actorID *int64, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	result, err := m.DB.Exec(query, leftAt, groupID, userID)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = changeMember(ctx, tx, groupID, userID, actorID, query, args...)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	return tx.Commit()
+}
+
+// changeMember runs a statement that affects the user's membership of the
+// group as part of tx, auditing it and reporting ErrRecordNotFound if no row
+// matched.
+func changeMember(ctx context.Context, tx *sql.Tx, groupID, userID int64, actorID *int64, query string, args ...any) error {
+	id, err := memberID(ctx, tx, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	return audited(ctx, tx, actorID, AuditEntityMember, &id, func() error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+// memberID looks up the ID of the user's membership of the group, which is 0
+// if they have never been a member.
+func memberID(ctx context.Context, q queryer, groupID, userID int64) (int64, error) {
+	query := `
+		SELECT id
+		FROM group_members
+		WHERE group_id = $1 AND user_id = $2`
+
+	var id int64
+
+	err := q.QueryRowContext(ctx, query, groupID, userID).Scan(&id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
 	}
 
-	return nil
+	return id, nil
 }
 
 func (m GroupMemberModel) UserBelongsToGroup(userID, groupID int64) (bool, error) {
@@ -110,12 +247,13 @@ func (m GroupMemberModel) CheckIfUserWasInGroup(groupID, userID int64) (bool, er
 	return count > 0, nil
 }
 
-func (m GroupMemberModel) ReinstateMember(groupID, userID int64) error {
+// ReinstateMember brings back a member who left or was removed. They return
+// as a plain member, whatever role they had before.
+func (m GroupMemberModel) ReinstateMember(groupID, userID int64, actorID *int64) error {
 	query := `
 		UPDATE group_members
-		SET is_active = true, left_at = NULL
+		SET is_active = true, left_at = NULL, role = 'member'
 		WHERE group_id = $1 AND user_id = $2 AND is_active = false`
 
-	_, err := m.DB.Exec(query, groupID, userID)
-	return err
+	return m.change(query, groupID, userID, actorID, groupID, userID)
 }
@@ -5,14 +5,17 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/manuelam2003/triclone/internal/storage"
 	"github.com/manuelam2003/triclone/internal/validator"
 )
 
 type Group struct {
 	ID        int64     `json:"id"`
 	Name      string    `json:"name"`
+	Currency  string    `json:"currency"`
 	CreatedBy *int64    `json:"created_by"`
 	CreatedAt time.Time `json:"-"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -22,26 +25,62 @@ func ValidateGroup(v *validator.Validator, group *Group) {
 	v.Check(group.Name != "", "name", "must be provided")
 	v.Check(len(group.Name) <= 500, "name", "must not be more than 500 bytes long")
 
-	v.Check(*group.CreatedBy != 0, "created_by", "must be provided")
-	v.Check(*group.CreatedBy > 0, "created_by", "must be a positive integer")
+	ValidateCurrency(v, "currency", group.Currency)
+
+	// The creator is cleared when their account is deleted, but the group
+	// lives on under its owner.
+	if group.CreatedBy != nil {
+		v.Check(*group.CreatedBy != 0, "created_by", "must be provided")
+		v.Check(*group.CreatedBy > 0, "created_by", "must be a positive integer")
+	}
 }
 
 type GroupModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Storage storage.Storage
+	Logger  *slog.Logger
 }
 
+// Insert creates the group and makes its creator the owner.
 func (m GroupModel) Insert(group *Group) error {
 	query := `
-		INSERT INTO groups (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO groups (name, currency, created_by)
+		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at`
 
-	args := []any{group.Name, group.CreatedBy}
+	memberQuery := `
+		INSERT INTO group_members (group_id, user_id, role)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+
+	args := []any{group.Name, group.Currency, group.CreatedBy}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = audited(ctx, tx, group.CreatedBy, AuditEntityGroup, &group.ID, func() error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	var ownerID int64
+
+	err = audited(ctx, tx, group.CreatedBy, AuditEntityMember, &ownerID, func() error {
+		return tx.QueryRowContext(ctx, memberQuery, group.ID, group.CreatedBy, RoleOwner).Scan(&ownerID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (m GroupModel) Get(id int64) (*Group, error) {
@@ -50,7 +89,7 @@ func (m GroupModel) Get(id int64) (*Group, error) {
 	}
 
 	query := `
-		SELECT id, name, created_by, created_at, updated_at
+		SELECT id, name, currency, created_by, created_at, updated_at
 		FROM groups
 		WHERE id = $1`
 
@@ -63,6 +102,7 @@ func (m GroupModel) Get(id int64) (*Group, error) {
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&group.ID,
 		&group.Name,
+		&group.Currency,
 		&group.CreatedBy,
 		&group.CreatedAt,
 		&group.UpdatedAt,
@@ -80,7 +120,7 @@ func (m GroupModel) Get(id int64) (*Group, error) {
 	return &group, nil
 }
 
-func (m GroupModel) Update(group *Group) error {
+func (m GroupModel) Update(group *Group, actorID *int64) error {
 	query := `
 		UPDATE groups
 		SET name = $1, created_by = $2, updated_at = NOW()
@@ -92,7 +132,15 @@ func (m GroupModel) Update(group *Group) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&group.UpdatedAt)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = audited(ctx, tx, actorID, AuditEntityGroup, &group.ID, func() error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&group.UpdatedAt)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -101,10 +149,11 @@ func (m GroupModel) Update(group *Group) error {
 			return err
 		}
 	}
-	return nil
+
+	return tx.Commit()
 }
 
-func (m GroupModel) Delete(id int64) error {
+func (m GroupModel) Delete(id int64, actorID *int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
@@ -116,26 +165,53 @@ func (m GroupModel) Delete(id int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	// The group's expenses and their attachments go with it, so their files
+	// have to be removed from storage as well.
+	keys, err := storageKeys(ctx, tx, `e.group_id = $1`, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = audited(ctx, tx, actorID, AuditEntityGroup, &id, func() error {
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	removeFiles(m.Storage, m.Logger, keys)
+
 	return nil
 }
 
 func (m GroupModel) GetAll(name string, createdBy int64, filters Filters) ([]*Group, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, name, created_by, created_at, updated_at
+		SELECT count(*) OVER(), id, name, currency, created_by, created_at, updated_at
 		FROM groups
 		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND (created_by = $2 OR $2 = 0)
@@ -164,6 +240,7 @@ func (m GroupModel) GetAll(name string, createdBy int64, filters Filters) ([]*Gr
 			&totalRecords,
 			&group.ID,
 			&group.Name,
+			&group.Currency,
 			&group.CreatedBy,
 			&group.CreatedAt,
 			&group.UpdatedAt,
@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LedgerEntry is a single change to a member's balance in a group, in the
+// group's base currency. As with Balance, positive amounts increase what the
+// member owes. EntryType is one of expense_share, payer_credit,
+// settlement_paid or settlement_received.
+type LedgerEntry struct {
+	ID             int64     `json:"id"`
+	GroupID        int64     `json:"group_id"`
+	UserID         int64     `json:"user_id"`
+	EntryType      string    `json:"entry_type"`
+	ExpenseID      *int64    `json:"expense_id,omitempty"`
+	SettlementID   *int64    `json:"settlement_id,omitempty"`
+	Amount         Money     `json:"amount"`
+	RunningBalance Money     `json:"running_balance"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+type LedgerDiscrepancy struct {
+	UserID   int64 `json:"user_id"`
+	Expected Money `json:"expected"`
+	Recorded Money `json:"recorded"`
+}
+
+// ledgerSourceQuery derives ledger rows from the base tables, leaving out
+// anything in the trash. Expenses are dated by the day they were incurred and
+// settlements by when they were settled. The first verb filters expenses
+// (aliased e) and the second filters settlements (aliased s).
+const ledgerSourceQuery = `
+	SELECT e.group_id, p.user_id, 'expense_share' AS entry_type, e.id AS expense_id, NULL::int AS settlement_id,
+		ROUND(p.amount_owed * e.exchange_rate, 2) AS amount, e.incurred_on::timestamp AS occurred_at
+	FROM expense_participants p
+	INNER JOIN expenses e ON e.id = p.expense_id
+	WHERE %[1]s AND e.deleted_at IS NULL
+	UNION ALL
+	SELECT e.group_id, e.paid_by, 'payer_credit', e.id, NULL,
+		-SUM(ROUND(p.amount_owed * e.exchange_rate, 2)), e.incurred_on::timestamp
+	FROM expense_participants p
+	INNER JOIN expenses e ON e.id = p.expense_id
+	WHERE %[1]s AND e.deleted_at IS NULL AND e.paid_by IS NOT NULL
+	GROUP BY e.group_id, e.paid_by, e.id, e.incurred_on
+	UNION ALL
+	SELECT s.group_id, s.payer_id, 'settlement_paid', NULL, s.id, -s.base_amount, s.settled_at
+	FROM settlements s
+	WHERE %[2]s AND s.deleted_at IS NULL AND s.payer_id IS NOT NULL
+	UNION ALL
+	SELECT s.group_id, s.payee_id, 'settlement_received', NULL, s.id, s.base_amount, s.settled_at
+	FROM settlements s
+	WHERE %[2]s AND s.deleted_at IS NULL AND s.payee_id IS NOT NULL`
+
+type LedgerModel struct {
+	DB *sql.DB
+}
+
+// SyncExpenseTx replaces the ledger entries of an expense with ones derived
+// from its current participants. It is run as part of the transaction that
+// changes the expense, so the ledger never drifts from it.
+func (m LedgerModel) SyncExpenseTx(q queryer, expenseID int64) error {
+	return m.replace(q, `expense_id = $1`, fmt.Sprintf(ledgerSourceQuery, `e.id = $1`, `FALSE`), expenseID)
+}
+
+// SyncSettlementTx replaces the ledger entries of a settlement as part of the
+// transaction that changes it.
+func (m LedgerModel) SyncSettlementTx(q queryer, settlementID int64) error {
+	return m.replace(q, `settlement_id = $1`, fmt.Sprintf(ledgerSourceQuery, `FALSE`, `s.id = $1`), settlementID)
+}
+
+// Rebuild throws away every ledger entry of a group and derives them again
+// from the expenses, participants and settlements tables.
+func (m LedgerModel) Rebuild(groupID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.replace(tx, `group_id = $1`, fmt.Sprintf(ledgerSourceQuery, `e.group_id = $1`, `s.group_id = $1`), groupID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m LedgerModel) replace(q queryer, where, source string, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := q.ExecContext(ctx, `DELETE FROM ledger_entries WHERE `+where, id)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ledger_entries (group_id, user_id, entry_type, expense_id, settlement_id, amount, occurred_at)
+		SELECT group_id, user_id, entry_type, expense_id, settlement_id, amount, occurred_at
+		FROM (` + source + `) source`
+
+	_, err = q.ExecContext(ctx, query, id)
+
+	return err
+}
+
+// Verify compares each member's ledger total with the total derived from the
+// base tables and returns the members whose totals differ.
+func (m LedgerModel) Verify(groupID int64) ([]LedgerDiscrepancy, error) {
+	query := fmt.Sprintf(`
+		WITH expected AS (
+			SELECT user_id, SUM(amount) AS total
+			FROM (%s) source
+			GROUP BY user_id
+		), recorded AS (
+			SELECT user_id, SUM(amount) AS total
+			FROM ledger_entries
+			WHERE group_id = $1
+			GROUP BY user_id
+		)
+		SELECT COALESCE(e.user_id, r.user_id), COALESCE(e.total, 0), COALESCE(r.total, 0)
+		FROM expected e
+		FULL OUTER JOIN recorded r ON r.user_id = e.user_id
+		WHERE COALESCE(e.total, 0) <> COALESCE(r.total, 0)
+		ORDER BY 1 ASC`, fmt.Sprintf(ledgerSourceQuery, `e.group_id = $1`, `s.group_id = $1`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discrepancies := []LedgerDiscrepancy{}
+
+	for rows.Next() {
+		var discrepancy LedgerDiscrepancy
+		if err := rows.Scan(&discrepancy.UserID, &discrepancy.Expected, &discrepancy.Recorded); err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
+
+// GetAllForMember lists a member's ledger entries. The running balance is
+// always accumulated in chronological order, whatever the requested sort.
+func (m LedgerModel) GetAllForMember(groupID, userID int64, filters Filters) ([]*LedgerEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, group_id, user_id, entry_type, expense_id, settlement_id, amount, running_balance, occurred_at
+		FROM (
+			SELECT *, SUM(amount) OVER (ORDER BY occurred_at ASC, id ASC) AS running_balance
+			FROM ledger_entries
+			WHERE group_id = $1 AND user_id = $2
+		) ledger
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*LedgerEntry{}
+
+	for rows.Next() {
+		var entry LedgerEntry
+		err := rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&entry.GroupID,
+			&entry.UserID,
+			&entry.EntryType,
+			&entry.ExpenseID,
+			&entry.SettlementID,
+			&entry.Amount,
+			&entry.RunningBalance,
+			&entry.OccurredAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
@@ -1,8 +1,12 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"log/slog"
+
+	"github.com/manuelam2003/triclone/internal/storage"
 )
 
 var (
@@ -12,26 +16,52 @@ var (
 	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
 )
 
+// queryer is satisfied by both *sql.DB and *sql.Tx, so the same query code can
+// run on its own or as part of a transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Models struct {
 	Groups               GroupModel
 	Users                UserModel
 	Tokens               TokenModel
 	GroupMembers         GroupMemberModel
+	GroupInvites         GroupInviteModel
 	Expenses             ExpenseModel
 	ExpensesParticipants ExpenseParticipantModel
 	Settlements          SettlementModel
 	Balances             BalanceModel
+	ExchangeRates        ExchangeRateModel
+	Ledger               LedgerModel
+	Categories           CategoryModel
+	RecurringExpenses    RecurringExpenseModel
+	Attachments          AttachmentModel
+	Comments             CommentModel
+	Activities           ActivityModel
+	Audit                AuditModel
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db *sql.DB, logger *slog.Logger, store storage.Storage) Models {
 	return Models{
-		Groups:               GroupModel{DB: db},
+		Groups:               GroupModel{DB: db, Storage: store, Logger: logger},
 		Users:                UserModel{DB: db},
 		Tokens:               TokenModel{DB: db},
 		GroupMembers:         GroupMemberModel{DB: db},
-		Expenses:             ExpenseModel{DB: db},
+		GroupInvites:         GroupInviteModel{DB: db},
+		Expenses:             ExpenseModel{DB: db, Storage: store, Logger: logger},
 		ExpensesParticipants: ExpenseParticipantModel{DB: db},
 		Settlements:          SettlementModel{DB: db},
-		Balances:             BalanceModel{DB: db},
+		Balances:             BalanceModel{DB: db, Logger: logger},
+		ExchangeRates:        ExchangeRateModel{DB: db},
+		Ledger:               LedgerModel{DB: db},
+		Categories:           CategoryModel{DB: db},
+		RecurringExpenses:    RecurringExpenseModel{DB: db},
+		Attachments:          AttachmentModel{DB: db},
+		Comments:             CommentModel{DB: db},
+		Activities:           ActivityModel{DB: db},
+		Audit:                AuditModel{DB: db},
 	}
 }
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact monetary amount stored as an integer number of minor
+// units (cents), matching the NUMERIC(10, 2) columns in the database.
+type Money int64
+
+const MaxMoney Money = 99_999_999_99
+
+var ErrInvalidMoneyFormat = errors.New("invalid monetary amount: must be a number with at most two decimal places")
+
+func ParseMoney(s string) (Money, error) {
+	value, ok := parseFixed(s, 2, 15)
+	if !ok {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	return Money(value), nil
+}
+
+func (m Money) String() string {
+	return formatFixed(int64(m), 2)
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+
+	return m
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	if string(jsonValue) == "null" {
+		return nil
+	}
+
+	value, err := ParseMoney(strings.Trim(string(jsonValue), `"`))
+	if err != nil {
+		return err
+	}
+
+	*m = value
+
+	return nil
+}
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v * 100)
+	case []byte:
+		value, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = value
+	case string:
+		value, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = value
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// parseFixed parses a plain decimal string with at most the given number of
+// fractional digits into an integer scaled by 10^places.
+func parseFixed(s string, places, maxDigits int) (int64, bool) {
+	s = strings.TrimSpace(s)
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	units, fraction, found := strings.Cut(s, ".")
+	if units == "" || len(units) > maxDigits || (found && (fraction == "" || len(fraction) > places)) {
+		return 0, false
+	}
+
+	for len(fraction) < places {
+		fraction += "0"
+	}
+
+	for _, r := range units + fraction {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+
+	value, err := strconv.ParseInt(units+fraction, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return value, true
+}
+
+func formatFixed(value int64, places int) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	scale := int64(1)
+	for i := 0; i < places; i++ {
+		scale *= 10
+	}
+
+	return fmt.Sprintf("%s%d.%0*d", sign, value/scale, places, value%scale)
+}
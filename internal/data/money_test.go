@@ -0,0 +1,198 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "12", want: 1200},
+		{input: "12.5", want: 1250},
+		{input: "12.34", want: 1234},
+		{input: "+12.34", want: 1234},
+		{input: " 12.34 ", want: 1234},
+		{input: "-12.34", want: -1234},
+		{input: "-0.01", want: -1},
+		{input: "99999999.99", want: MaxMoney},
+		{input: "100000000.00", want: MaxMoney + 1},
+		{input: "12.345", wantErr: true},
+		{input: "12.", wantErr: true},
+		{input: ".5", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "1e3", wantErr: true},
+		{input: "12,34", wantErr: true},
+		{input: "--1", wantErr: true},
+		{input: "1234567890123456", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want an error", tt.input, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseMoney(%q) returned error: %v", tt.input, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		input Money
+		want  string
+	}{
+		{input: 0, want: "0.00"},
+		{input: 5, want: "0.05"},
+		{input: 1234, want: "12.34"},
+		{input: -1, want: "-0.01"},
+		{input: -1234, want: "-12.34"},
+		{input: MaxMoney, want: "99999999.99"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.input.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: `{"amount": 12.34}`, want: 1234},
+		{input: `{"amount": "12.34"}`, want: 1234},
+		{input: `{"amount": 7}`, want: 700},
+		{input: `{"amount": "-0.50"}`, want: -50},
+		{input: `{"amount": null}`, want: 0},
+		{input: `{}`, want: 0},
+		{input: `{"amount": 12.345}`, wantErr: true},
+		{input: `{"amount": "12.345"}`, wantErr: true},
+		{input: `{"amount": "abc"}`, wantErr: true},
+		{input: `{"amount": true}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var input struct {
+			Amount Money `json:"amount"`
+		}
+
+		err := json.Unmarshal([]byte(tt.input), &input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %v, want an error", tt.input, input.Amount)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unmarshal %s returned error: %v", tt.input, err)
+			continue
+		}
+
+		if input.Amount != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.input, input.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	got, err := json.Marshal(map[string]Money{"amount": -1205})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"amount":-12.05}`; string(got) != want {
+		t.Errorf("marshal = %s, want %s", got, want)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{src: []byte("12.34"), want: 1234},
+		{src: []byte("-0.07"), want: -7},
+		{src: "12.30", want: 1230},
+		{src: int64(12), want: 1200},
+		{src: int64(-3), want: -300},
+		{src: nil, want: 0},
+		{src: []byte("12.345"), wantErr: true},
+		{src: "abc", wantErr: true},
+		{src: 12.34, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		m := Money(99)
+
+		err := m.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %v, want an error", tt.src, m)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Scan(%#v) returned error: %v", tt.src, err)
+			continue
+		}
+
+		if m != tt.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tt.src, m, tt.want)
+		}
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		amount Money
+		rate   string
+		want   Money
+	}{
+		{amount: 1234, rate: "1", want: 1234},
+		{amount: 1000, rate: "1.5", want: 1500},
+		{amount: 1, rate: "0.5", want: 1},
+		{amount: 1, rate: "0.49999999", want: 0},
+		{amount: -1, rate: "0.5", want: -1},
+		{amount: -1, rate: "0.49999999", want: 0},
+		{amount: 3, rate: "0.5", want: 2},
+		{amount: -3, rate: "0.5", want: -2},
+		{amount: 10000, rate: "0.00012345", want: 1},
+		{amount: 9999, rate: "1.23456789", want: 12344},
+		{amount: 0, rate: "1.23456789", want: 0},
+		{amount: MaxMoney, rate: "9999999999.99999999", want: Money(math.MaxInt64)},
+	}
+
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatalf("ParseRate(%q) returned error: %v", tt.rate, err)
+		}
+
+		if got := tt.amount.Convert(rate); got != tt.want {
+			t.Errorf("Money(%d).Convert(%s) = %d, want %d", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
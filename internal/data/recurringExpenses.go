@@ -0,0 +1,487 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// maxOccurrencesPerRun bounds how many overdue occurrences of a single
+// recurring expense are materialized at once, so catching up after a long
+// outage cannot hold a transaction open for too long.
+const maxOccurrencesPerRun = 100
+
+var ErrMissingExchangeRate = errors.New("missing exchange rate")
+
+// RecurringExpense is a template that is turned into a regular expense on
+// every occurrence between its start and end dates.
+type RecurringExpense struct {
+	ID             int64     `json:"id"`
+	GroupID        int64     `json:"group_id"`
+	Amount         Money     `json:"amount"`
+	Currency       string    `json:"currency"`
+	Description    string    `json:"description"`
+	PaidBy         *int64    `json:"paid_by"`
+	CategoryID     *int64    `json:"category_id"`
+	Frequency      string    `json:"frequency"`
+	StartDate      Date      `json:"start_date"`
+	EndDate        *Date     `json:"end_date"`
+	Split          Split     `json:"split"`
+	Occurrences    int       `json:"occurrences"`
+	NextOccurrence *Date     `json:"next_occurrence"`
+	CreatedAt      time.Time `json:"-"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func ValidateRecurringExpense(v *validator.Validator, recurring *RecurringExpense) {
+	v.Check(recurring.GroupID > 0, "group_id", "must be positive")
+	v.Check(recurring.Amount > 0, "amount", "must be positive")
+	v.Check(recurring.Amount <= MaxMoney, "amount", "must not be more than "+MaxMoney.String())
+
+	ValidateCurrency(v, "currency", recurring.Currency)
+	v.Check(recurring.Description != "", "description", "must be provided")
+	v.Check(len(recurring.Description) <= 255, "description", "must not be more than 255 bytes long")
+
+	v.Check(validator.PermittedValue(recurring.Frequency, FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly), "frequency", "must be one of daily, weekly, monthly or yearly")
+	v.Check(!recurring.StartDate.IsZero(), "start_date", "must be provided")
+
+	if recurring.EndDate != nil {
+		v.Check(!recurring.EndDate.Before(recurring.StartDate.Time), "end_date", "must not be before start_date")
+	}
+
+	ValidateSplit(v, &recurring.Split, recurring.Amount)
+}
+
+// occurrence returns the date of the n-th occurrence, counting from zero.
+// Monthly and yearly occurrences are always computed from the start date so
+// that an expense starting on the 31st falls on the last day of shorter months
+// without drifting to the 28th for the rest of the year.
+func (r *RecurringExpense) occurrence(n int) Date {
+	start := r.StartDate.Time
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return Date{start.AddDate(0, 0, 7*n)}
+	case FrequencyMonthly:
+		return Date{addMonthsClamped(start, n)}
+	case FrequencyYearly:
+		return Date{addMonthsClamped(start, 12*n)}
+	default:
+		return Date{start.AddDate(0, 0, n)}
+	}
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+
+	return time.Date(first.Year(), first.Month(), min(t.Day(), lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+// ScheduleNext sets NextOccurrence to the first occurrence that has not been
+// materialized yet, or to nil once that would fall after the end date.
+func (r *RecurringExpense) ScheduleNext() {
+	next := r.occurrence(r.Occurrences)
+
+	if r.EndDate != nil && next.After(r.EndDate.Time) {
+		r.NextOccurrence = nil
+		return
+	}
+
+	r.NextOccurrence = &next
+}
+
+type RecurringExpenseModel struct {
+	DB *sql.DB
+}
+
+const recurringExpenseColumns = `id, group_id, amount, currency, description, paid_by, category_id, frequency, start_date, end_date, split, occurrences, next_occurrence, created_at, updated_at`
+
+// scanRecurringExpense scans a single row selected with recurringExpenseColumns
+// and decodes its split.
+func scanRecurringExpense(row *sql.Row, recurring *RecurringExpense) error {
+	var split []byte
+
+	err := row.Scan(
+		&recurring.ID,
+		&recurring.GroupID,
+		&recurring.Amount,
+		&recurring.Currency,
+		&recurring.Description,
+		&recurring.PaidBy,
+		&recurring.CategoryID,
+		&recurring.Frequency,
+		&recurring.StartDate,
+		&recurring.EndDate,
+		&split,
+		&recurring.Occurrences,
+		&recurring.NextOccurrence,
+		&recurring.CreatedAt,
+		&recurring.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(split, &recurring.Split)
+}
+
+func (m RecurringExpenseModel) Insert(recurring *RecurringExpense) error {
+	query := `
+		INSERT INTO recurring_expenses (group_id, amount, currency, description, paid_by, category_id, frequency, start_date, end_date, split, occurrences, next_occurrence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`
+
+	split, err := json.Marshal(recurring.Split)
+	if err != nil {
+		return err
+	}
+
+	args := []any{
+		recurring.GroupID,
+		recurring.Amount,
+		recurring.Currency,
+		recurring.Description,
+		recurring.PaidBy,
+		recurring.CategoryID,
+		recurring.Frequency,
+		recurring.StartDate,
+		recurring.EndDate,
+		split,
+		recurring.Occurrences,
+		recurring.NextOccurrence,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&recurring.ID, &recurring.CreatedAt, &recurring.UpdatedAt)
+}
+
+func (m RecurringExpenseModel) Get(groupID, recurringID int64) (*RecurringExpense, error) {
+	query := `
+		SELECT ` + recurringExpenseColumns + `
+		FROM recurring_expenses
+		WHERE id = $1 AND group_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var recurring RecurringExpense
+
+	err := scanRecurringExpense(m.DB.QueryRowContext(ctx, query, recurringID, groupID), &recurring)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &recurring, nil
+}
+
+func (m RecurringExpenseModel) GetAllForGroup(groupID int64, filters Filters) ([]*RecurringExpense, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), %s
+		FROM recurring_expenses
+		WHERE group_id = $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, recurringExpenseColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	recurringExpenses := []*RecurringExpense{}
+
+	for rows.Next() {
+		var recurring RecurringExpense
+		var split []byte
+
+		err := rows.Scan(
+			&totalRecords,
+			&recurring.ID,
+			&recurring.GroupID,
+			&recurring.Amount,
+			&recurring.Currency,
+			&recurring.Description,
+			&recurring.PaidBy,
+			&recurring.CategoryID,
+			&recurring.Frequency,
+			&recurring.StartDate,
+			&recurring.EndDate,
+			&split,
+			&recurring.Occurrences,
+			&recurring.NextOccurrence,
+			&recurring.CreatedAt,
+			&recurring.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if err := json.Unmarshal(split, &recurring.Split); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		recurringExpenses = append(recurringExpenses, &recurring)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return recurringExpenses, metadata, nil
+}
+
+func (m RecurringExpenseModel) Update(recurring *RecurringExpense) error {
+	query := `
+		UPDATE recurring_expenses
+		SET amount = $1, description = $2, category_id = $3, end_date = $4, split = $5, next_occurrence = $6, updated_at = NOW()
+		WHERE id = $7 AND updated_at = $8
+		RETURNING updated_at`
+
+	split, err := json.Marshal(recurring.Split)
+	if err != nil {
+		return err
+	}
+
+	args := []any{
+		recurring.Amount,
+		recurring.Description,
+		recurring.CategoryID,
+		recurring.EndDate,
+		split,
+		recurring.NextOccurrence,
+		recurring.ID,
+		recurring.UpdatedAt,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&recurring.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a recurring expense. Expenses it already created are kept.
+func (m RecurringExpenseModel) Delete(groupID, recurringID int64) error {
+	query := `
+		DELETE FROM recurring_expenses
+		WHERE id = $1 AND group_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, recurringID, groupID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetDue returns the IDs of the recurring expenses with an occurrence on or
+// before the given day that has not been materialized yet.
+func (m RecurringExpenseModel) GetDue(today Date) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM recurring_expenses
+		WHERE next_occurrence <= $1
+		ORDER BY next_occurrence ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Materialize creates an expense, with its participants, for every occurrence
+// of the recurring expense up to and including today, and advances the
+// recurring expense past them, all in one transaction. The row is locked while
+// this happens and skipped if another process holds the lock, so running it
+// concurrently or again after a crash never creates an occurrence twice.
+func (m RecurringExpenseModel) Materialize(recurringID int64, today Date) ([]*Expense, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + recurringExpenseColumns + `
+		FROM recurring_expenses
+		WHERE id = $1 AND next_occurrence <= $2
+		FOR UPDATE SKIP LOCKED`
+
+	var recurring RecurringExpense
+
+	err = scanRecurringExpense(tx.QueryRowContext(ctx, query, recurringID, today), &recurring)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	expenses := []*Expense{}
+
+	// Without a payer there is nobody to credit, so the schedule ends here.
+	if recurring.PaidBy == nil {
+		recurring.NextOccurrence = nil
+		return expenses, m.advance(ctx, tx, &recurring)
+	}
+
+	rate, err := m.exchangeRate(ctx, tx, &recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	expenseModel := ExpenseModel{DB: m.DB}
+
+	for i := 0; i < maxOccurrencesPerRun && recurring.NextOccurrence != nil && !recurring.NextOccurrence.After(today.Time); i++ {
+		expense := &Expense{
+			GroupID:            recurring.GroupID,
+			Amount:             recurring.Amount,
+			Currency:           recurring.Currency,
+			ExchangeRate:       rate,
+			BaseAmount:         recurring.Amount.Convert(rate),
+			Description:        recurring.Description,
+			PaidBy:             recurring.PaidBy,
+			CategoryID:         recurring.CategoryID,
+			IncurredOn:         *recurring.NextOccurrence,
+			RecurringExpenseID: &recurring.ID,
+		}
+
+		// The server creates these expenses on its own, so there is no actor.
+		err = audited(ctx, tx, nil, AuditEntityExpense, &expense.ID, func() error {
+			return expenseModel.insertWithParticipants(tx, expense, recurring.Split.Allocate(recurring.Amount))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = LedgerModel{DB: m.DB}.SyncExpenseTx(tx, expense.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		expenses = append(expenses, expense)
+
+		recurring.Occurrences++
+		recurring.ScheduleNext()
+	}
+
+	err = m.advance(ctx, tx, &recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	return expenses, nil
+}
+
+func (m RecurringExpenseModel) advance(ctx context.Context, tx *sql.Tx, recurring *RecurringExpense) error {
+	query := `
+		UPDATE recurring_expenses
+		SET occurrences = $1, next_occurrence = $2
+		WHERE id = $3`
+
+	_, err := tx.ExecContext(ctx, query, recurring.Occurrences, recurring.NextOccurrence, recurring.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// exchangeRate looks up the rate that converts the recurring expense's
+// currency into its group's base currency at the time it is materialized.
+func (m RecurringExpenseModel) exchangeRate(ctx context.Context, tx *sql.Tx, recurring *RecurringExpense) (Rate, error) {
+	query := `
+		SELECT g.currency, er.rate
+		FROM groups g
+		LEFT JOIN exchange_rates er ON er.group_id = g.id AND er.currency = $2
+		WHERE g.id = $1`
+
+	var baseCurrency string
+	var rate *Rate
+
+	err := tx.QueryRowContext(ctx, query, recurring.GroupID, recurring.Currency).Scan(&baseCurrency, &rate)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case recurring.Currency == baseCurrency:
+		return RateOne, nil
+	case rate == nil:
+		return 0, ErrMissingExchangeRate
+	default:
+		return *rate, nil
+	}
+}
@@ -0,0 +1,59 @@
+package data
+
+import "slices"
+
+// A member's role in a group decides what they are allowed to do in it. Each
+// group has exactly one owner.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var Roles = []string{RoleOwner, RoleAdmin, RoleMember, RoleViewer}
+
+type Permission string
+
+const (
+	// PermissionView allows reading everything in the group.
+	PermissionView Permission = "view"
+	// PermissionWrite allows adding and editing expenses, settlements and
+	// the records attached to them.
+	PermissionWrite Permission = "write"
+	// PermissionDelete allows moving expenses and settlements to the trash
+	// and restoring or purging them.
+	PermissionDelete Permission = "delete"
+	// PermissionManageGroup allows changing the group's settings.
+	PermissionManageGroup Permission = "manage_group"
+	// PermissionManageMembers allows removing and reinstating members and
+	// changing their roles.
+	PermissionManageMembers Permission = "manage_members"
+	// PermissionOwn allows deleting the group and handing it over to
+	// another member.
+	PermissionOwn Permission = "own"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer: {PermissionView},
+	RoleMember: {PermissionView, PermissionWrite},
+	RoleAdmin:  {PermissionView, PermissionWrite, PermissionDelete, PermissionManageGroup, PermissionManageMembers},
+	RoleOwner:  {PermissionView, PermissionWrite, PermissionDelete, PermissionManageGroup, PermissionManageMembers, PermissionOwn},
+}
+
+var roleRanks = map[string]int{
+	RoleViewer: 0,
+	RoleMember: 1,
+	RoleAdmin:  2,
+	RoleOwner:  3,
+}
+
+func RoleHasPermission(role string, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// RoleOutranks reports whether a member with role can manage a member with
+// other. Nobody outranks someone of their own rank.
+func RoleOutranks(role, other string) bool {
+	return roleRanks[role] > roleRanks[other]
+}
@@ -11,12 +11,17 @@ import (
 )
 
 type Settlement struct {
-	ID        int64     `json:"id"`
-	GroupID   int64     `json:"group_id"`
-	PayerID   *int64    `json:"payer_id"`
-	PayeeID   *int64    `json:"payee_id"`
-	Amount    float64   `json:"amount"`
-	SettledAt time.Time `json:"settled_at"`
+	ID           int64      `json:"id"`
+	GroupID      int64      `json:"group_id"`
+	PayerID      *int64     `json:"payer_id"`
+	PayeeID      *int64     `json:"payee_id"`
+	Amount       Money      `json:"amount"`
+	Currency     string     `json:"currency"`
+	ExchangeRate Rate       `json:"exchange_rate"`
+	BaseAmount   Money      `json:"base_amount"`
+	SettledAt    time.Time  `json:"settled_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy    *int64     `json:"deleted_by,omitempty"`
 }
 
 func ValidateSettlement(v *validator.Validator, settlement *Settlement) {
@@ -35,24 +40,36 @@ func ValidateSettlement(v *validator.Validator, settlement *Settlement) {
 	}
 
 	v.Check(settlement.Amount > 0, "amount", "must be positive")
+	v.Check(settlement.Amount <= MaxMoney, "amount", "must not be more than "+MaxMoney.String())
+	v.Check(settlement.BaseAmount <= MaxMoney, "amount", "must not be more than "+MaxMoney.String()+" once converted to the group's currency")
+
+	ValidateCurrency(v, "currency", settlement.Currency)
+	v.Check(settlement.ExchangeRate > 0, "exchange_rate", "must be positive")
+
+	v.Check(!settlement.SettledAt.IsZero(), "settled_at", "must be provided")
+	v.Check(!settlement.SettledAt.After(time.Now()), "settled_at", "must not be in the future")
 }
 
 type SettlementModel struct {
 	DB *sql.DB
 }
 
-func (m SettlementModel) GetAllForGroup(groupID int64, filters Filters) ([]*Settlement, Metadata, error) {
+// GetAllForGroup lists a group's settlements, optionally limited to those
+// settled between the from and to dates, both inclusive.
+func (m SettlementModel) GetAllForGroup(groupID int64, from, to *Date, filters Filters) ([]*Settlement, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, group_id, payer_id, payee_id, amount, settled_at
+		SELECT count(*) OVER(), id, group_id, payer_id, payee_id, amount, currency, exchange_rate, base_amount, settled_at
 		FROM settlements
-		WHERE group_id = $1
-		ORDER BY %s %s
-		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+		WHERE group_id = $1 AND deleted_at IS NULL
+		AND ($2::date IS NULL OR settled_at >= $2::date)
+		AND ($3::date IS NULL OR settled_at < $3::date + 1)
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.limit(), filters.offset())
+	rows, err := m.DB.QueryContext(ctx, query, groupID, from, to, filters.limit(), filters.offset())
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -71,6 +88,9 @@ func (m SettlementModel) GetAllForGroup(groupID int64, filters Filters) ([]*Sett
 			&settlement.PayerID,
 			&settlement.PayeeID,
 			&settlement.Amount,
+			&settlement.Currency,
+			&settlement.ExchangeRate,
+			&settlement.BaseAmount,
 			&settlement.SettledAt,
 		)
 		if err != nil {
@@ -88,9 +108,9 @@ func (m SettlementModel) GetAllForGroup(groupID int64, filters Filters) ([]*Sett
 
 func (m SettlementModel) Get(settlementID int64, groupID int64) (*Settlement, error) {
 	query := `
-		SELECT id, group_id, payer_id, payee_id, amount, settled_at
+		SELECT id, group_id, payer_id, payee_id, amount, currency, exchange_rate, base_amount, settled_at
 		FROM settlements
-		WHERE id = $1 AND group_id = $2`
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NULL`
 
 	var settlement Settlement
 
@@ -100,6 +120,9 @@ func (m SettlementModel) Get(settlementID int64, groupID int64) (*Settlement, er
 		&settlement.PayerID,
 		&settlement.PayeeID,
 		&settlement.Amount,
+		&settlement.Currency,
+		&settlement.ExchangeRate,
+		&settlement.BaseAmount,
 		&settlement.SettledAt,
 	)
 
@@ -113,16 +136,128 @@ func (m SettlementModel) Get(settlementID int64, groupID int64) (*Settlement, er
 	return &settlement, nil
 }
 
-func (m SettlementModel) Insert(settlement *Settlement) error {
+func (m SettlementModel) Insert(settlement *Settlement, actorID *int64) error {
+	return m.InsertAll([]*Settlement{settlement}, actorID)
+}
+
+// InsertTx records the settlement as part of tx without auditing it.
+func (m SettlementModel) InsertTx(tx *sql.Tx, settlement *Settlement) error {
+	return m.insert(tx, settlement)
+}
+
+// InsertAll records a batch of settlements in a single transaction.
+func (m SettlementModel) InsertAll(settlements []*Settlement, actorID *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.insertAll(ctx, tx, settlements, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SettleUp records the transfers that bring every balance in the group back
+// to zero. The group's row stays locked while its balances are worked out and
+// settled, so a concurrent or retried request waits for this one and then
+// finds nothing left to settle.
+func (m SettlementModel) SettleUp(groupID int64, actorID *int64) ([]*Settlement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT currency
+		FROM groups
+		WHERE id = $1
+		FOR UPDATE`
+
+	var currency string
+
+	err = tx.QueryRowContext(ctx, query, groupID).Scan(&currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	balances, err := groupBalances(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements := []*Settlement{}
+	settledAt := time.Now()
+
+	for _, transfer := range SimplifyDebts(balances) {
+		settlements = append(settlements, &Settlement{
+			GroupID:      groupID,
+			PayerID:      &transfer.From,
+			PayeeID:      &transfer.To,
+			Amount:       transfer.Amount,
+			Currency:     currency,
+			ExchangeRate: RateOne,
+			BaseAmount:   transfer.Amount,
+			SettledAt:    settledAt,
+		})
+	}
+
+	err = m.insertAll(ctx, tx, settlements, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return settlements, nil
+}
+
+func (m SettlementModel) insertAll(ctx context.Context, tx *sql.Tx, settlements []*Settlement, actorID *int64) error {
+	for _, settlement := range settlements {
+		err := audited(ctx, tx, actorID, AuditEntitySettlement, &settlement.ID, func() error {
+			return m.InsertTx(tx, settlement)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = LedgerModel{DB: m.DB}.SyncSettlementTx(tx, settlement.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m SettlementModel) insert(q queryer, settlement *Settlement) error {
 	query := `
-		INSERT INTO settlements (group_id, payer_id, payee_id, amount, settled_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO settlements (group_id, payer_id, payee_id, amount, currency, exchange_rate, base_amount, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, settled_at`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	// Execute the query, and scan the returned `id` and `settled_at` fields into the settlement object
-	err := m.DB.QueryRowContext(ctx, query, settlement.GroupID, settlement.PayerID, settlement.PayeeID, settlement.Amount, settlement.SettledAt).Scan(
+	err := q.QueryRowContext(ctx, query, settlement.GroupID, settlement.PayerID, settlement.PayeeID, settlement.Amount, settlement.Currency, settlement.ExchangeRate, settlement.BaseAmount, settlement.SettledAt).Scan(
 		&settlement.ID, &settlement.SettledAt,
 	)
 
@@ -133,27 +268,124 @@ func (m SettlementModel) Insert(settlement *Settlement) error {
 	return nil
 }
 
-func (m SettlementModel) Delete(groupID, settlementID int64) error {
+// SoftDelete moves the settlement to the group's trash.
+func (m SettlementModel) SoftDelete(groupID, settlementID int64, actorID *int64) error {
+	query := `
+		UPDATE settlements
+		SET deleted_at = NOW(), deleted_by = $1
+		WHERE id = $2 AND group_id = $3 AND deleted_at IS NULL`
+
+	return m.change(query, settlementID, actorID, actorID, settlementID, groupID)
+}
+
+// Restore takes the settlement back out of the trash.
+func (m SettlementModel) Restore(groupID, settlementID int64, actorID *int64) error {
+	query := `
+		UPDATE settlements
+		SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NOT NULL`
+
+	return m.change(query, settlementID, actorID, settlementID, groupID)
+}
+
+// Purge permanently removes a settlement from the trash.
+func (m SettlementModel) Purge(groupID, settlementID int64, actorID *int64) error {
 	query := `
 		DELETE FROM settlements
-		WHERE id = $1 AND group_id = $2`
+		WHERE id = $1 AND group_id = $2 AND deleted_at IS NOT NULL`
+
+	return m.change(query, settlementID, actorID, settlementID, groupID)
+}
 
+// change runs a statement that affects a single settlement, auditing it,
+// syncing its ledger entries and reporting ErrRecordNotFound if no row matched.
+func (m SettlementModel) change(query string, settlementID int64, actorID *int64, args ...any) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, settlementID, groupID)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	err = audited(ctx, tx, actorID, AuditEntitySettlement, &settlementID, func() error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = LedgerModel{DB: m.DB}.SyncSettlementTx(tx, settlementID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// GetAllDeleted lists the settlements in the group's trash.
+func (m SettlementModel) GetAllDeleted(groupID int64, filters Filters) ([]*Settlement, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, group_id, payer_id, payee_id, amount, currency, exchange_rate, base_amount, settled_at, deleted_at, deleted_by
+		FROM settlements
+		WHERE group_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, groupID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	settlements := []*Settlement{}
+
+	for rows.Next() {
+		var settlement Settlement
+		err := rows.Scan(
+			&totalRecords,
+			&settlement.ID,
+			&settlement.GroupID,
+			&settlement.PayerID,
+			&settlement.PayeeID,
+			&settlement.Amount,
+			&settlement.Currency,
+			&settlement.ExchangeRate,
+			&settlement.BaseAmount,
+			&settlement.SettledAt,
+			&settlement.DeletedAt,
+			&settlement.DeletedBy,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		settlements = append(settlements, &settlement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return settlements, metadata, nil
 }
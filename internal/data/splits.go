@@ -0,0 +1,196 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+const (
+	SplitEqual      = "equal"
+	SplitExact      = "exact"
+	SplitPercentage = "percentage"
+	SplitShares     = "shares"
+)
+
+const maxSplitShares = 1_000_000
+
+// Percentage is a percentage with two decimal places, stored as basis points.
+type Percentage int64
+
+const fullPercentage Percentage = 100_00
+
+func (p Percentage) String() string {
+	return formatFixed(int64(p), 2)
+}
+
+func (p Percentage) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Percentage) UnmarshalJSON(jsonValue []byte) error {
+	if string(jsonValue) == "null" {
+		return nil
+	}
+
+	value, ok := parseFixed(strings.Trim(string(jsonValue), `"`), 2, 3)
+	if !ok {
+		return fmt.Errorf("invalid percentage: must be a number with at most two decimal places")
+	}
+
+	*p = Percentage(value)
+
+	return nil
+}
+
+type SplitParticipant struct {
+	UserID     int64       `json:"user_id"`
+	Amount     *Money      `json:"amount,omitempty"`
+	Percentage *Percentage `json:"percentage,omitempty"`
+	Shares     *int64      `json:"shares,omitempty"`
+}
+
+type Split struct {
+	Method       string             `json:"method"`
+	Participants []SplitParticipant `json:"participants"`
+}
+
+func ValidateSplit(v *validator.Validator, split *Split, total Money) {
+	v.Check(validator.PermittedValue(split.Method, SplitEqual, SplitExact, SplitPercentage, SplitShares), "split.method", "must be one of equal, exact, percentage or shares")
+	v.Check(len(split.Participants) > 0, "split.participants", "must contain at least one participant")
+
+	userIDs := make([]int64, len(split.Participants))
+	for i, participant := range split.Participants {
+		userIDs[i] = participant.UserID
+		v.Check(participant.UserID > 0, "split.participants", "user_id must be positive")
+	}
+	v.Check(validator.Unique(userIDs), "split.participants", "must not contain duplicate users")
+
+	if !v.Valid() {
+		return
+	}
+
+	switch split.Method {
+	case SplitExact:
+		var sum Money
+		for _, participant := range split.Participants {
+			if participant.Amount == nil || *participant.Amount <= 0 {
+				v.AddError("split.participants", "every participant must have a positive amount")
+				return
+			}
+			sum += *participant.Amount
+		}
+		v.Check(sum == total, "split.participants", fmt.Sprintf("amounts must add up to the expense amount %s, got %s", total, sum))
+
+	case SplitPercentage:
+		var sum Percentage
+		for _, participant := range split.Participants {
+			if participant.Percentage == nil || *participant.Percentage <= 0 {
+				v.AddError("split.participants", "every participant must have a positive percentage")
+				return
+			}
+			sum += *participant.Percentage
+		}
+		v.Check(sum == fullPercentage, "split.participants", fmt.Sprintf("percentages must add up to 100, got %s", sum))
+
+	case SplitShares:
+		for _, participant := range split.Participants {
+			if participant.Shares == nil || *participant.Shares <= 0 || *participant.Shares > maxSplitShares {
+				v.AddError("split.participants", fmt.Sprintf("every participant must have between 1 and %d shares", maxSplitShares))
+				return
+			}
+		}
+	}
+
+	if !v.Valid() {
+		return
+	}
+
+	for _, participant := range split.Allocate(total) {
+		if participant.AmountOwed <= 0 {
+			v.AddError("split", "the amount is too small to be split between this many participants")
+			return
+		}
+	}
+}
+
+// Allocate computes what each participant owes for an expense of the given
+// total. Participants are ordered by user ID and any cents left over after
+// rounding down go to the largest remainders first, so the result always adds
+// up to the total and does not depend on the order the client sent them in.
+// The split must have been validated with ValidateSplit beforehand.
+func (s Split) Allocate(total Money) []*ExpenseParticipant {
+	participants := make([]SplitParticipant, len(s.Participants))
+	copy(participants, s.Participants)
+
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].UserID < participants[j].UserID
+	})
+
+	weights := make([]int64, len(participants))
+	for i, participant := range participants {
+		switch s.Method {
+		case SplitPercentage:
+			weights[i] = int64(*participant.Percentage)
+		case SplitShares:
+			weights[i] = *participant.Shares
+		default:
+			weights[i] = 1
+		}
+	}
+
+	amounts := total.allocate(weights)
+
+	allocated := make([]*ExpenseParticipant, len(participants))
+	for i, participant := range participants {
+		allocated[i] = &ExpenseParticipant{
+			UserID:     participant.UserID,
+			AmountOwed: amounts[i],
+		}
+
+		if s.Method == SplitExact {
+			allocated[i].AmountOwed = *participant.Amount
+		}
+	}
+
+	return allocated
+}
+
+func (m Money) allocate(weights []int64) []Money {
+	var totalWeight int64
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	amounts := make([]Money, len(weights))
+	if totalWeight == 0 {
+		return amounts
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := Money(0)
+
+	for i, weight := range weights {
+		amounts[i] = Money(int64(m) * weight / totalWeight)
+		remainders[i] = int64(m) * weight % totalWeight
+		allocated += amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+
+	for i := 0; allocated < m; i++ {
+		amounts[order[i%len(order)]]++
+		allocated++
+	}
+
+	return amounts
+}
@@ -0,0 +1,197 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/manuelam2003/triclone/internal/validator"
+)
+
+func decodeSplit(t *testing.T, input string) Split {
+	t.Helper()
+
+	var split Split
+	if err := json.Unmarshal([]byte(input), &split); err != nil {
+		t.Fatalf("unmarshal %s returned error: %v", input, err)
+	}
+
+	return split
+}
+
+func TestSplitAllocate(t *testing.T) {
+	tests := []struct {
+		name  string
+		split string
+		total Money
+		want  map[int64]Money
+	}{
+		{
+			name:  "equal split that divides evenly",
+			split: `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 2}]}`,
+			total: 1000,
+			want:  map[int64]Money{1: 500, 2: 500},
+		},
+		{
+			name:  "equal split with a leftover cent",
+			split: `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 2}, {"user_id": 3}]}`,
+			total: 10000,
+			want:  map[int64]Money{1: 3334, 2: 3333, 3: 3333},
+		},
+		{
+			name:  "leftover cents go to the lowest user IDs whatever the order",
+			split: `{"method": "equal", "participants": [{"user_id": 9}, {"user_id": 4}, {"user_id": 7}]}`,
+			total: 200,
+			want:  map[int64]Money{4: 67, 7: 67, 9: 66},
+		},
+		{
+			name:  "exact amounts are kept",
+			split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 12.34}, {"user_id": 2, "amount": "7.66"}]}`,
+			total: 2000,
+			want:  map[int64]Money{1: 1234, 2: 766},
+		},
+		{
+			name:  "percentages in basis points",
+			split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 33.33}, {"user_id": 2, "percentage": 33.33}, {"user_id": 3, "percentage": 33.34}]}`,
+			total: 10000,
+			want:  map[int64]Money{1: 3333, 2: 3333, 3: 3334},
+		},
+		{
+			name:  "percentages with a leftover cent go to the largest remainder",
+			split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 50}, {"user_id": 2, "percentage": 25.5}, {"user_id": 3, "percentage": 24.5}]}`,
+			total: 101,
+			want:  map[int64]Money{1: 50, 2: 26, 3: 25},
+		},
+		{
+			name:  "shares",
+			split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 2}, {"user_id": 2, "shares": 1}]}`,
+			total: 1000,
+			want:  map[int64]Money{1: 667, 2: 333},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := decodeSplit(t, tt.split)
+
+			v := validator.New()
+			if ValidateSplit(v, &split, tt.total); !v.Valid() {
+				t.Fatalf("ValidateSplit returned errors: %v", v.Errors)
+			}
+
+			allocated := split.Allocate(tt.total)
+			if len(allocated) != len(tt.want) {
+				t.Fatalf("got %d participants, want %d", len(allocated), len(tt.want))
+			}
+
+			var sum Money
+			for i, participant := range allocated {
+				if i > 0 && allocated[i-1].UserID >= participant.UserID {
+					t.Errorf("participants are not ordered by user ID")
+				}
+
+				if want := tt.want[participant.UserID]; participant.AmountOwed != want {
+					t.Errorf("user %d owes %s, want %s", participant.UserID, participant.AmountOwed, want)
+				}
+				sum += participant.AmountOwed
+			}
+
+			if sum != tt.total {
+				t.Errorf("amounts add up to %s, want %s", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestSplitAllocateIsDeterministic(t *testing.T) {
+	forward := decodeSplit(t, `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 2}, {"user_id": 3}, {"user_id": 4}, {"user_id": 5}, {"user_id": 6}]}`)
+	backward := decodeSplit(t, `{"method": "equal", "participants": [{"user_id": 6}, {"user_id": 5}, {"user_id": 4}, {"user_id": 3}, {"user_id": 2}, {"user_id": 1}]}`)
+
+	a := forward.Allocate(1003)
+	b := backward.Allocate(1003)
+
+	for i := range a {
+		if *a[i] != *b[i] {
+			t.Errorf("participant %d: got %+v and %+v depending on input order", i, *a[i], *b[i])
+		}
+	}
+}
+
+func TestValidateSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		split string
+		total Money
+		valid bool
+	}{
+		{name: "equal", split: `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 2}]}`, total: 100, valid: true},
+		{name: "unknown method", split: `{"method": "random", "participants": [{"user_id": 1}]}`, total: 100},
+		{name: "no participants", split: `{"method": "equal", "participants": []}`, total: 100},
+		{name: "duplicate users", split: `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 1}]}`, total: 100},
+		{name: "non-positive user ID", split: `{"method": "equal", "participants": [{"user_id": 0}]}`, total: 100},
+		{name: "too small to split", split: `{"method": "equal", "participants": [{"user_id": 1}, {"user_id": 2}, {"user_id": 3}]}`, total: 2},
+		{name: "exact amounts that add up", split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 0.60}, {"user_id": 2, "amount": 0.40}]}`, total: 100, valid: true},
+		{name: "exact amounts that fall short", split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 0.60}, {"user_id": 2, "amount": 0.39}]}`, total: 100},
+		{name: "exact amount missing", split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 1}, {"user_id": 2}]}`, total: 100},
+		{name: "zero exact amount", split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 1}, {"user_id": 2, "amount": 0}]}`, total: 100},
+		{name: "negative exact amount", split: `{"method": "exact", "participants": [{"user_id": 1, "amount": 1.50}, {"user_id": 2, "amount": -0.50}]}`, total: 100},
+		{name: "percentages that add up", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 99.99}, {"user_id": 2, "percentage": 0.01}]}`, total: 10000, valid: true},
+		{name: "percentages under 100", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 50}, {"user_id": 2, "percentage": 49.99}]}`, total: 100},
+		{name: "percentages over 100", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 50}, {"user_id": 2, "percentage": 50.01}]}`, total: 100},
+		{name: "zero percentage", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 100}, {"user_id": 2, "percentage": 0}]}`, total: 100},
+		{name: "negative percentage", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 110}, {"user_id": 2, "percentage": -10}]}`, total: 100},
+		{name: "missing percentage", split: `{"method": "percentage", "participants": [{"user_id": 1, "percentage": 100}, {"user_id": 2}]}`, total: 100},
+		{name: "shares", split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 3}, {"user_id": 2, "shares": 1}]}`, total: 100, valid: true},
+		{name: "zero shares", split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 1}, {"user_id": 2, "shares": 0}]}`, total: 100},
+		{name: "negative shares", split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 2}, {"user_id": 2, "shares": -1}]}`, total: 100},
+		{name: "too many shares", split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 1000001}]}`, total: 100},
+		{name: "missing shares", split: `{"method": "shares", "participants": [{"user_id": 1, "shares": 1}, {"user_id": 2}]}`, total: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := decodeSplit(t, tt.split)
+
+			v := validator.New()
+			ValidateSplit(v, &split, tt.total)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("valid = %t, want %t (errors: %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestPercentageUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Percentage
+		wantErr bool
+	}{
+		{input: `33.33`, want: 33_33},
+		{input: `"12.5"`, want: 12_50},
+		{input: `100`, want: fullPercentage},
+		{input: `33.333`, wantErr: true},
+		{input: `1000`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var p Percentage
+
+		err := json.Unmarshal([]byte(tt.input), &p)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %v, want an error", tt.input, p)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unmarshal %s returned error: %v", tt.input, err)
+			continue
+		}
+
+		if p != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.input, p, tt.want)
+		}
+	}
+}
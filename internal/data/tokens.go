@@ -6,22 +6,51 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/manuelam2003/triclone/internal/validator"
 )
 
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
+	ScopeRefresh        = "refresh"
 )
 
+// sessionScopes are the scopes of the tokens that make up a session.
+var sessionScopes = []string{ScopeAuthentication, ScopeRefresh}
+
+// ErrTokenReused is returned when a refresh token that has already been
+// exchanged is presented again, which means it has most likely been stolen.
+var ErrTokenReused = errors.New("refresh token reused")
+
 type Token struct {
+	ID        int64     `json:"-"`
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	FamilyID  int64     `json:"-"`
+	UserAgent string    `json:"-"`
+	IP        string    `json:"-"`
+}
+
+// Session describes one login without revealing its tokens, so users can see
+// where they are signed in. Its ID is the family the login's access and
+// refresh tokens belong to, and Current marks the session the request was
+// made with.
+type Session struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Expiry     time.Time  `json:"expiry"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
@@ -31,19 +60,30 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 		Scope:  scope,
 	}
 
-	randomBytes := make([]byte, 16)
+	var err error
 
-	_, err := rand.Read(randomBytes)
+	token.Plaintext, token.Hash, err = randomToken()
 	if err != nil {
 		return nil, err
 	}
 
-	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	return token, nil
+}
+
+// randomToken returns a random 26 character plaintext along with the SHA-256
+// hash that is stored in its place.
+func randomToken() (string, []byte, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
 
-	hash := sha256.Sum256([]byte(token.Plaintext))
-	token.Hash = hash[:]
+	plaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(plaintext))
 
-	return token, nil
+	return plaintext, hash[:], nil
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
@@ -65,18 +105,159 @@ func (t TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
-func (t TokenModel) Insert(token *Token) error {
+// NewSession starts a new session for the user with a short-lived access
+// token and a long-lived refresh token, recording which client they were
+// issued to. No access token is issued when accessTTL is zero.
+func (t TokenModel) NewSession(userID int64, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	access, refresh, err := insertSessionTokens(ctx, tx, userID, 0, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token in the same session. The old refresh token is kept, marked as used,
+// so that if it is presented again the whole session is revoked and
+// ErrTokenReused returned. Older access tokens of the session stop working.
+// As with NewSession, no access token is issued when accessTTL is zero.
+func (t TokenModel) Refresh(tokenPlaintext string, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
 	query := `
-        INSERT INTO tokens (hash, user_id, expiry, scope) 
-        VALUES ($1, $2, $3, $4)`
+        SELECT id, user_id, family_id, used_at
+        FROM tokens
+        WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+        FOR UPDATE`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var id, userID, familyID int64
+	var usedAt *time.Time
+
+	err = tx.QueryRowContext(ctx, query, tokenHash[:], ScopeRefresh).Scan(&id, &userID, &familyID, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if usedAt != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family_id = $1`, familyID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, ErrTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family_id = $1 AND scope = $2`, familyID, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertSessionTokens(ctx, tx, userID, familyID, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+// insertSessionTokens issues an access and a refresh token in the given
+// family, or in a new one when familyID is zero. With a zero accessTTL only
+// the refresh token is issued, for when access tokens are not kept in the
+// database.
+func insertSessionTokens(ctx context.Context, q queryer, userID, familyID int64, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	var access *Token
+	var tokens []*Token
+
+	if accessTTL > 0 {
+		var err error
+
+		access, err = generateToken(userID, accessTTL, ScopeAuthentication)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tokens = append(tokens, access)
+	}
+
+	refresh, err := generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens = append(tokens, refresh)
+
+	for _, token := range tokens {
+		token.FamilyID = familyID
+		token.UserAgent = userAgent
+		token.IP = ip
+
+		err = insertToken(ctx, q, token)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+		familyID = token.FamilyID
+	}
+
+	return access, refresh, nil
+}
 
+func (t TokenModel) Insert(token *Token) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := t.DB.ExecContext(ctx, query, args...)
-	return err
+	return insertToken(ctx, t.DB, token)
+}
+
+// insertToken stores token in its family, or starts a new family for it when
+// FamilyID is zero.
+func insertToken(ctx context.Context, q queryer, token *Token) error {
+	query := `
+        INSERT INTO tokens (hash, user_id, expiry, scope, family_id, user_agent, ip) 
+        VALUES ($1, $2, $3, $4, COALESCE($5, nextval('token_families')), $6, $7)
+        RETURNING id, family_id`
+
+	var familyID *int64
+	if token.FamilyID != 0 {
+		familyID = &token.FamilyID
+	}
+
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, familyID, token.UserAgent, token.IP}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.FamilyID)
 }
 
 func (t TokenModel) DeleteAllForUser(scope string, userID int64) error {
@@ -90,3 +271,111 @@ func (t TokenModel) DeleteAllForUser(scope string, userID int64) error {
 	_, err := t.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// Touch records that an authentication token has been used. To keep writes
+// off the path of every request, last_used_at is only updated once it is
+// more than a minute old, so it is accurate to the minute.
+func (t TokenModel) Touch(tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+        UPDATE tokens
+        SET last_used_at = NOW()
+        WHERE hash = $1 AND scope = $2
+        AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, tokenHash[:], ScopeAuthentication)
+	return err
+}
+
+// GetAllSessionsForUser returns the user's sessions that can still be used,
+// most recently used first. currentID is the session to mark as current.
+func (t TokenModel) GetAllSessionsForUser(userID, currentID int64) ([]*Session, error) {
+	query := `
+        SELECT family_id, MIN(created_at), MAX(last_used_at), MAX(expiry),
+            (array_agg(user_agent ORDER BY id DESC))[1], (array_agg(ip ORDER BY id DESC))[1]
+        FROM tokens
+        WHERE user_id = $1 AND scope = ANY($2) AND expiry > NOW() AND used_at IS NULL
+        GROUP BY family_id
+        ORDER BY COALESCE(MAX(last_used_at), MIN(created_at)) DESC, family_id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, userID, pq.Array(sessionScopes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.UserAgent,
+			&session.IP,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.Current = session.ID == currentID
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession revokes all of the tokens in one of the user's sessions.
+func (t TokenModel) DeleteSession(userID, sessionID int64) error {
+	query := `
+        DELETE FROM tokens
+        WHERE family_id = $1 AND user_id = $2 AND scope = ANY($3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := t.DB.ExecContext(ctx, query, sessionID, userID, pq.Array(sessionScopes))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions revokes every session the user has except the one with
+// keepID.
+func (t TokenModel) DeleteOtherSessions(userID, keepID int64) error {
+	query := `
+        DELETE FROM tokens
+        WHERE user_id = $1 AND scope = ANY($2) AND family_id <> $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, userID, pq.Array(sessionScopes), keepID)
+	return err
+}
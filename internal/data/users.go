@@ -23,6 +23,7 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
+	IsAdmin   bool      `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"-"`
 }
@@ -118,7 +119,7 @@ func (m UserModel) Insert(user *User) error {
 
 func (m UserModel) GetByID(id int64) (*User, error) {
 	query := `
-        SELECT id, name, email, password_hash, activated, created_at, updated_at
+        SELECT id, name, email, password_hash, activated, is_admin, created_at, updated_at
         FROM users
         WHERE id = $1`
 
@@ -133,6 +134,7 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -151,7 +153,7 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-        SELECT id, name, email, password_hash, activated, created_at, updated_at
+        SELECT id, name, email, password_hash, activated, is_admin, created_at, updated_at
         FROM users
         WHERE email = $1`
 
@@ -166,6 +168,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -220,7 +223,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-        SELECT users.id, users.name, users.email, users.password_hash, users.activated, users.created_at, users.updated_at
+        SELECT users.id, users.name, users.email, users.password_hash, users.activated, users.is_admin, users.created_at, users.updated_at
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -241,6 +244,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -395,3 +399,46 @@ func (m UserModel) GetAllByGroup(groupID int64, isActive string, filters Filters
 
 	return users, metadata, nil
 }
+
+// GetForSession looks up the user an authentication token belongs to, along
+// with the ID of the session it is part of.
+func (m UserModel) GetForSession(tokenPlaintext string) (*User, int64, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+        SELECT tokens.family_id, users.id, users.name, users.email, users.password_hash, users.activated, users.is_admin, users.created_at, users.updated_at
+        FROM users
+        INNER JOIN tokens
+        ON users.id = tokens.user_id
+        WHERE tokens.hash = $1
+        AND tokens.scope = $2
+        AND tokens.expiry > $3`
+
+	var user User
+	var sessionID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], ScopeAuthentication, time.Now()).Scan(
+		&sessionID,
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.IsAdmin,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, 0, ErrRecordNotFound
+		default:
+			return nil, 0, err
+		}
+	}
+
+	return &user, sessionID, nil
+}
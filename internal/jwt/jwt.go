@@ -0,0 +1,169 @@
+// Package jwt signs and verifies compact JSON Web Tokens with HMAC-SHA256
+// (HS256) or Ed25519 (EdDSA) keys. Tokens carry the ID of the key that signed
+// them in the "kid" header, so keys can be rotated by adding a new signing key
+// while keeping the old ones around for verification until the tokens they
+// signed have expired.
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// RegisteredClaims are the standard claims every token carries. Applications
+// embed them in their own claims type.
+type RegisteredClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+func (c *RegisteredClaims) registered() *RegisteredClaims {
+	return c
+}
+
+// Claims is implemented by any type that embeds RegisteredClaims.
+type Claims interface {
+	registered() *RegisteredClaims
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Keyset signs tokens with one of its keys and verifies tokens signed by any
+// of them, for a single issuer and audience.
+type Keyset struct {
+	keys     map[string]*Key
+	signing  *Key
+	issuer   string
+	audience string
+}
+
+// NewKeyset returns a Keyset that signs with the key with signingKeyID, or
+// with the first key when signingKeyID is empty.
+func NewKeyset(keys []*Key, signingKeyID, issuer, audience string) (*Keyset, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: no keys")
+	}
+
+	ks := &Keyset{
+		keys:     make(map[string]*Key, len(keys)),
+		issuer:   issuer,
+		audience: audience,
+	}
+
+	for _, key := range keys {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, errors.New("jwt: duplicate key ID " + key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	if signingKeyID == "" {
+		signingKeyID = keys[0].ID
+	}
+
+	ks.signing = ks.keys[signingKeyID]
+	if ks.signing == nil {
+		return nil, errors.New("jwt: unknown signing key ID " + signingKeyID)
+	}
+
+	return ks, nil
+}
+
+// Sign fills in the issuer, audience, issue time and expiry of claims and
+// returns them as a signed token.
+func (ks *Keyset) Sign(claims Claims, expiry time.Time) (string, error) {
+	registered := claims.registered()
+	registered.Issuer = ks.issuer
+	registered.Audience = ks.audience
+	registered.IssuedAt = time.Now().Unix()
+	registered.ExpiresAt = expiry.Unix()
+
+	h, err := json.Marshal(header{Algorithm: ks.signing.Algorithm, Type: "JWT", KeyID: ks.signing.ID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(h) + "." + encode(payload)
+
+	return signingInput + "." + encode(ks.signing.sign([]byte(signingInput))), nil
+}
+
+// Verify checks the token's signature, expiry, issuer and audience and
+// decodes its payload into claims. The algorithm in the header has to match
+// the key's, so a token cannot pick a weaker way of being checked.
+func (ks *Keyset) Verify(token string, claims Claims) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return ErrInvalidToken
+	}
+
+	key := ks.keys[h.KeyID]
+	if key == nil || h.Algorithm != key.Algorithm {
+		return ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return ErrInvalidToken
+	}
+
+	if err := decodeJSON(parts[1], claims); err != nil {
+		return ErrInvalidToken
+	}
+
+	registered := claims.registered()
+
+	if registered.Issuer != ks.issuer || registered.Audience != ks.audience {
+		return ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= registered.ExpiresAt {
+		return ErrExpiredToken
+	}
+
+	return nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJSON(segment string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	return dec.Decode(dst)
+}
@@ -0,0 +1,380 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	RegisteredClaims
+	Name string `json:"name"`
+}
+
+func mustHS256Key(t *testing.T, id string) *Key {
+	t.Helper()
+
+	key, err := NewHS256Key(id, bytes.Repeat([]byte(id[:1]), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func mustEdDSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+
+	key, err := NewEdDSAKey(id, bytes.Repeat([]byte(id[:1]), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func mustKeyset(t *testing.T, keys []*Key, signingKeyID string) *Keyset {
+	t.Helper()
+
+	ks, err := NewKeyset(keys, signingKeyID, "triclone", "triclone-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ks
+}
+
+// signRaw builds a token from an arbitrary header and payload, signed with
+// key, to test tokens that Sign would never produce.
+func signRaw(t *testing.T, key *Key, h, payload any) string {
+	t.Helper()
+
+	hb, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pb, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := encode(hb) + "." + encode(pb)
+
+	return signingInput + "." + encode(key.sign([]byte(signingInput)))
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":  "triclone",
+		"aud":  "triclone-api",
+		"sub":  "42",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+		"name": "Alice",
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		key  func(*testing.T, string) *Key
+	}{
+		{name: "HS256", key: mustHS256Key},
+		{name: "EdDSA", key: mustEdDSAKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := mustKeyset(t, []*Key{tt.key(t, "k1")}, "")
+
+			token, err := ks.Sign(&testClaims{RegisteredClaims: RegisteredClaims{Subject: "42"}, Name: "Alice"}, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("Sign returned error: %v", err)
+			}
+
+			var h header
+			if err := decodeJSON(strings.Split(token, ".")[0], &h); err != nil {
+				t.Fatal(err)
+			}
+
+			if h.Algorithm != tt.name || h.KeyID != "k1" || h.Type != "JWT" {
+				t.Errorf("header = %+v, want alg %s and kid k1", h, tt.name)
+			}
+
+			var claims testClaims
+			if err := ks.Verify(token, &claims); err != nil {
+				t.Fatalf("Verify returned error: %v", err)
+			}
+
+			if claims.Subject != "42" || claims.Name != "Alice" || claims.Issuer != "triclone" || claims.Audience != "triclone-api" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			if claims.IssuedAt == 0 || claims.ExpiresAt <= claims.IssuedAt {
+				t.Errorf("iat = %d and exp = %d, want exp after iat", claims.IssuedAt, claims.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	hsKey := mustHS256Key(t, "hs")
+	edKey := mustEdDSAKey(t, "ed")
+	otherKey := mustHS256Key(t, "xx")
+	otherKey.ID = "hs"
+
+	ks := mustKeyset(t, []*Key{hsKey, edKey}, "hs")
+
+	valid, err := ks.Sign(&testClaims{Name: "Alice"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, ".")
+
+	tamperedPayload := validClaims()
+	tamperedPayload["sub"] = "1"
+
+	flipped := []byte(parts[2])
+	if flipped[0] == 'A' {
+		flipped[0] = 'B'
+	} else {
+		flipped[0] = 'A'
+	}
+
+	withClaim := func(key string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	// An HS256 token keyed with the EdDSA key's public key, as in the
+	// classic algorithm confusion attack.
+	confused := &Key{ID: "ed", Algorithm: AlgorithmHS256, secret: edKey.privateKey.Public().(ed25519.PublicKey)}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "empty", token: "", want: ErrInvalidToken},
+		{name: "two segments", token: parts[0] + "." + parts[1], want: ErrInvalidToken},
+		{name: "four segments", token: valid + ".x", want: ErrInvalidToken},
+		{name: "header not base64", token: "!!!." + parts[1] + "." + parts[2], want: ErrInvalidToken},
+		{name: "header not JSON", token: encode([]byte("nope")) + "." + parts[1] + "." + parts[2], want: ErrInvalidToken},
+		{name: "tampered payload", token: parts[0] + "." + encode(mustJSON(t, tamperedPayload)) + "." + parts[2], want: ErrInvalidToken},
+		{name: "tampered signature", token: parts[0] + "." + parts[1] + "." + string(flipped), want: ErrInvalidToken},
+		{name: "signature not base64", token: parts[0] + "." + parts[1] + ".!!!", want: ErrInvalidToken},
+		{name: "no signature", token: parts[0] + "." + parts[1] + ".", want: ErrInvalidToken},
+		{name: "signed by another key with the same kid", token: signRaw(t, otherKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, validClaims()), want: ErrInvalidToken},
+		{name: "HS256 header with an EdDSA kid", token: signRaw(t, confused, header{Algorithm: AlgorithmHS256, KeyID: "ed"}, validClaims()), want: ErrInvalidToken},
+		{name: "EdDSA header with an HS256 kid", token: signRaw(t, edKey, header{Algorithm: AlgorithmEdDSA, KeyID: "hs"}, validClaims()), want: ErrInvalidToken},
+		{name: "none algorithm", token: encode(mustJSON(t, header{Algorithm: "none", KeyID: "hs"})) + "." + encode(mustJSON(t, validClaims())) + ".", want: ErrInvalidToken},
+		{name: "unknown kid", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "unknown"}, validClaims()), want: ErrInvalidToken},
+		{name: "missing kid", token: signRaw(t, hsKey, map[string]string{"alg": AlgorithmHS256, "typ": "JWT"}, validClaims()), want: ErrInvalidToken},
+		{name: "wrong issuer", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("iss", "someone-else")), want: ErrInvalidToken},
+		{name: "missing issuer", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("iss", nil)), want: ErrInvalidToken},
+		{name: "wrong audience", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("aud", "another-api")), want: ErrInvalidToken},
+		{name: "missing audience", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("aud", nil)), want: ErrInvalidToken},
+		{name: "payload not JSON", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, "not an object"), want: ErrInvalidToken},
+		{name: "expired", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("exp", time.Now().Add(-time.Second).Unix())), want: ErrExpiredToken},
+		{name: "expires now", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("exp", time.Now().Unix())), want: ErrExpiredToken},
+		{name: "exp of zero", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("exp", 0)), want: ErrExpiredToken},
+		{name: "missing exp", token: signRaw(t, hsKey, header{Algorithm: AlgorithmHS256, KeyID: "hs"}, withClaim("exp", nil)), want: ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims testClaims
+
+			err := ks.Verify(tt.token, &claims)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	var claims testClaims
+	if err := ks.Verify(valid, &claims); err != nil {
+		t.Fatalf("Verify rejected the untampered token: %v", err)
+	}
+}
+
+func TestSignExpired(t *testing.T) {
+	ks := mustKeyset(t, []*Key{mustHS256Key(t, "k1")}, "")
+
+	for _, expiry := range []time.Time{time.Now().Add(-time.Minute), time.Unix(0, 0)} {
+		token, err := ks.Sign(&testClaims{}, expiry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var claims testClaims
+		if err := ks.Verify(token, &claims); !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("Verify of a token that expired at %v returned %v, want %v", expiry, err, ErrExpiredToken)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := mustHS256Key(t, "old")
+	newKey := mustEdDSAKey(t, "new")
+
+	before := mustKeyset(t, []*Key{oldKey}, "")
+
+	oldToken, err := before.Sign(&testClaims{Name: "old"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key is added and becomes the signing key, while the old one is
+	// kept for the tokens it has already signed.
+	during := mustKeyset(t, []*Key{oldKey, newKey}, "new")
+
+	newToken, err := during.Sign(&testClaims{Name: "new"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var h header
+	if err := decodeJSON(strings.Split(newToken, ".")[0], &h); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.KeyID != "new" {
+		t.Errorf("new tokens are signed with kid %q, want %q", h.KeyID, "new")
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		var claims testClaims
+		if err := during.Verify(token, &claims); err != nil {
+			t.Errorf("Verify of the %s token during rotation returned %v", claims.Name, err)
+		}
+	}
+
+	// Once the old key is removed, only the new tokens verify.
+	after := mustKeyset(t, []*Key{newKey}, "")
+
+	var claims testClaims
+	if err := after.Verify(newToken, &claims); err != nil {
+		t.Errorf("Verify of the new token after rotation returned %v", err)
+	}
+
+	if err := after.Verify(oldToken, &claims); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify of the old token after its key was removed returned %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestNewKeyset(t *testing.T) {
+	k1 := mustHS256Key(t, "k1")
+	k2 := mustEdDSAKey(t, "k2")
+
+	tests := []struct {
+		name         string
+		keys         []*Key
+		signingKeyID string
+		wantSigning  string
+		wantErr      bool
+	}{
+		{name: "first key signs by default", keys: []*Key{k1, k2}, wantSigning: "k1"},
+		{name: "chosen signing key", keys: []*Key{k1, k2}, signingKeyID: "k2", wantSigning: "k2"},
+		{name: "no keys", wantErr: true},
+		{name: "unknown signing key", keys: []*Key{k1}, signingKeyID: "k2", wantErr: true},
+		{name: "duplicate key IDs", keys: []*Key{k1, k1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := NewKeyset(tt.keys, tt.signingKeyID, "triclone", "triclone-api")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("NewKeyset returned no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("NewKeyset returned error: %v", err)
+			}
+
+			if ks.signing.ID != tt.wantSigning {
+				t.Errorf("signing key = %q, want %q", ks.signing.ID, tt.wantSigning)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("s"), 32))
+	seed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("e"), 32))
+	short := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("s"), 31))
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "empty", spec: ""},
+		{name: "one HS256 key", spec: "a:HS256:" + secret, wantIDs: []string{"a"}},
+		{name: "one EdDSA key", spec: "b:EdDSA:" + seed, wantIDs: []string{"b"}},
+		{name: "several keys with spaces", spec: " a:HS256:" + secret + " , b:EdDSA:" + seed + ",", wantIDs: []string{"a", "b"}},
+		{name: "ID with a dash", spec: "2024-06:EdDSA:" + seed, wantIDs: []string{"2024-06"}},
+		{name: "missing fields", spec: "a:HS256", wantErr: true},
+		{name: "only an ID", spec: "a", wantErr: true},
+		{name: "empty ID", spec: ":HS256:" + secret, wantErr: true},
+		{name: "invalid base64", spec: "a:HS256:not base64!", wantErr: true},
+		{name: "URL-safe base64", spec: "a:HS256:" + strings.NewReplacer("+", "-", "/", "_").Replace(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xfb}, 32))), wantErr: true},
+		{name: "unsupported algorithm", spec: "a:RS256:" + secret, wantErr: true},
+		{name: "lowercase algorithm", spec: "a:hs256:" + secret, wantErr: true},
+		{name: "HS256 secret too short", spec: "a:HS256:" + short, wantErr: true},
+		{name: "EdDSA seed too short", spec: "a:EdDSA:" + short, wantErr: true},
+		{name: "EdDSA seed too long", spec: "a:EdDSA:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("e"), 64)), wantErr: true},
+		{name: "one bad key among good ones", spec: "a:HS256:" + secret + ",b:HS256:" + short, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeys(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseKeys returned %d keys and no error", len(keys))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseKeys returned error: %v", err)
+			}
+
+			if len(keys) != len(tt.wantIDs) {
+				t.Fatalf("got %d keys, want %d", len(keys), len(tt.wantIDs))
+			}
+
+			for i, key := range keys {
+				if key.ID != tt.wantIDs[i] {
+					t.Errorf("key %d has ID %q, want %q", i, key.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
@@ -0,0 +1,111 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a named signing key. HS256 keys are shared secrets of at least 32
+// bytes and EdDSA keys are Ed25519 private keys.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret     []byte
+	privateKey ed25519.PrivateKey
+}
+
+func NewHS256Key(id string, secret []byte) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("jwt: HS256 key %s must be at least 32 bytes long", id)
+	}
+
+	return &Key{ID: id, Algorithm: AlgorithmHS256, secret: secret}, nil
+}
+
+// NewEdDSAKey derives an Ed25519 key from a 32 byte seed.
+func NewEdDSAKey(id string, seed []byte) (*Key, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("jwt: EdDSA key %s must be a %d byte seed", id, ed25519.SeedSize)
+	}
+
+	return &Key{ID: id, Algorithm: AlgorithmEdDSA, privateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// ParseKeys reads a comma-separated list of keys in the form
+// "id:algorithm:material", where material is the base64-encoded secret for
+// HS256 or seed for EdDSA. For example:
+//
+//	2024-06:EdDSA:3q2+7w...,2024-01:HS256:c2VjcmV0...
+func ParseKeys(spec string) ([]*Key, error) {
+	var keys []*Key
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.SplitN(entry, ":", 3)
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, errors.New("jwt: keys must be in the form id:algorithm:material")
+		}
+
+		material, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %s is not valid base64", fields[0])
+		}
+
+		var key *Key
+
+		switch fields[1] {
+		case AlgorithmHS256:
+			key, err = NewHS256Key(fields[0], material)
+		case AlgorithmEdDSA:
+			key, err = NewEdDSAKey(fields[0], material)
+		default:
+			err = fmt.Errorf("jwt: key %s has unsupported algorithm %s", fields[0], fields[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (k *Key) sign(data []byte) []byte {
+	switch k.Algorithm {
+	case AlgorithmEdDSA:
+		return ed25519.Sign(k.privateKey, data)
+	default:
+		return hs256(k.secret, data)
+	}
+}
+
+func (k *Key) verify(data, signature []byte) bool {
+	switch k.Algorithm {
+	case AlgorithmEdDSA:
+		return ed25519.Verify(k.privateKey.Public().(ed25519.PublicKey), data, signature)
+	default:
+		return hmac.Equal(hs256(k.secret, data), signature)
+	}
+}
+
+func hs256(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"time"
+
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+// Mailer delivers email messages, such as the activation and password reset
+// emails sent to users.
+type Mailer interface {
+	// Send delivers msg to its recipient.
+	Send(msg *Message) error
+}
+
+type Message struct {
+	To        string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// NewMessage renders the named template from the templates directory for
+// recipient. Each template defines a "subject", a "plainBody" and an
+// "htmlBody".
+func NewMessage(recipient, templateFile string, data any) (*Message, error) {
+	textTmpl, err := texttemplate.New("").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmltemplate.New("").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{To: recipient}
+
+	var buf bytes.Buffer
+
+	if err = textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, err
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err = textTmpl.ExecuteTemplate(&buf, "plainBody", data); err != nil {
+		return nil, err
+	}
+	msg.PlainBody = buf.String()
+
+	buf.Reset()
+	if err = htmlTmpl.ExecuteTemplate(&buf, "htmlBody", data); err != nil {
+		return nil, err
+	}
+	msg.HTMLBody = buf.String()
+
+	return msg, nil
+}
+
+// writeTo writes msg as a multipart/alternative email from sender, with the
+// plain text body first so clients prefer the HTML one.
+func (msg *Message) writeTo(w io.Writer, sender string) error {
+	body := multipart.NewWriter(w)
+
+	headers := []struct{ key, value string }{
+		{"From", sender},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", body.Boundary())},
+	}
+
+	for _, h := range headers {
+		_, err := fmt.Fprintf(w, "%s: %s\r\n", h.key, h.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, "\r\n"); err != nil {
+		return err
+	}
+
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.PlainBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	}
+
+	for _, p := range parts {
+		part, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(part, p.content); err != nil {
+			return err
+		}
+	}
+
+	return body.Close()
+}
@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMessage(t *testing.T) {
+	expiry := time.Date(2030, time.March, 4, 15, 30, 0, 0, time.UTC)
+
+	// The data mirrors what the handlers in cmd/api pass to each template.
+	tests := []struct {
+		name         string
+		templateFile string
+		data         map[string]any
+		subject      string
+		want         []string
+	}{
+		{
+			name:         "welcome",
+			templateFile: "user_welcome.tmpl",
+			data: map[string]any{
+				"name":            "Alice",
+				"activationToken": "ACTIVATIONTOKEN",
+			},
+			subject: "Welcome to Triclone!",
+			want:    []string{"Alice", "ACTIVATIONTOKEN"},
+		},
+		{
+			name:         "password reset",
+			templateFile: "password_reset.tmpl",
+			data: map[string]any{
+				"passwordResetToken": "RESETTOKEN",
+			},
+			subject: "Reset your Triclone password",
+			want:    []string{"RESETTOKEN"},
+		},
+		{
+			name:         "group invite with expiry",
+			templateFile: "group_invite.tmpl",
+			data: map[string]any{
+				"inviterName": "Bob",
+				"groupName":   "Flatmates",
+				"inviteToken": "INVITETOKEN",
+				"expiry":      &expiry,
+			},
+			subject: "You have been invited to Flatmates on Triclone",
+			want:    []string{"Bob", "Flatmates", "INVITETOKEN", "4 March 2030 at 15:30 UTC"},
+		},
+		{
+			name:         "group invite without expiry",
+			templateFile: "group_invite.tmpl",
+			data: map[string]any{
+				"inviterName": "Bob",
+				"groupName":   "Flatmates",
+				"inviteToken": "INVITETOKEN",
+				"expiry":      (*time.Time)(nil),
+			},
+			subject: "You have been invited to Flatmates on Triclone",
+			want:    []string{"Bob", "Flatmates", "INVITETOKEN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := NewMessage("alice@example.com", tt.templateFile, tt.data)
+			if err != nil {
+				t.Fatalf("NewMessage returned error: %v", err)
+			}
+
+			if msg.To != "alice@example.com" {
+				t.Errorf("To = %q, want %q", msg.To, "alice@example.com")
+			}
+
+			if msg.Subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.subject)
+			}
+
+			for _, body := range []struct{ name, content string }{{"plain", msg.PlainBody}, {"HTML", msg.HTMLBody}} {
+				if strings.Contains(body.content, "<no value>") {
+					t.Errorf("%s body refers to data that was not passed:\n%s", body.name, body.content)
+				}
+
+				for _, want := range tt.want {
+					if !strings.Contains(body.content, want) {
+						t.Errorf("%s body does not contain %q:\n%s", body.name, want, body.content)
+					}
+				}
+			}
+
+			if tt.data["expiry"] == (*time.Time)(nil) && strings.Contains(msg.PlainBody, "expires") {
+				t.Errorf("plain body mentions an expiry for an invite without one:\n%s", msg.PlainBody)
+			}
+		})
+	}
+}
+
+func TestNewMessageUnknownTemplate(t *testing.T) {
+	_, err := NewMessage("alice@example.com", "missing.tmpl", nil)
+	if err == nil {
+		t.Error("NewMessage returned no error for a template that does not exist")
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Outbox writes messages to .eml files in a directory instead of sending
+// them, for development and tests. Every message is logged along with the
+// file it was written to.
+type Outbox struct {
+	dir    string
+	sender string
+	logger *slog.Logger
+}
+
+func NewOutbox(dir, sender string, logger *slog.Logger) (*Outbox, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outbox{dir: dir, sender: sender, logger: logger}, nil
+}
+
+func (o *Outbox) Send(msg *Message) error {
+	suffix := make([]byte, 4)
+
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return err
+	}
+
+	// Names sort in the order the messages were sent.
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	path := filepath.Join(o.dir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+
+	err = msg.writeTo(f, o.sender)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	o.logger.Info("email written to outbox", "to", msg.To, "subject", msg.Subject, "file", path)
+
+	return nil
+}
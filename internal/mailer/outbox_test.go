@@ -0,0 +1,116 @@
+package mailer
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutboxSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+
+	outbox, err := NewOutbox(dir, "Triclone <no-reply@example.com>", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewOutbox returned error: %v", err)
+	}
+
+	msg, err := NewMessage("alice@example.com", "password_reset.tmpl", map[string]any{"passwordResetToken": "RESETTOKEN"})
+	if err != nil {
+		t.Fatalf("NewMessage returned error: %v", err)
+	}
+
+	for range 2 {
+		if err = outbox.Send(msg); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("got %d files in the outbox, want 2", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	email, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("outbox file is not a valid email: %v", err)
+	}
+
+	headers := map[string]string{
+		"From":         "Triclone <no-reply@example.com>",
+		"To":           "alice@example.com",
+		"Mime-Version": "1.0",
+	}
+
+	for key, want := range headers {
+		if got := email.Header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(email.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", subject, msg.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(email.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", mediaType)
+	}
+
+	parts := multipart.NewReader(email.Body, params["boundary"])
+
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.PlainBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", want.contentType, err)
+		}
+
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(body) != want.body {
+			t.Errorf("%s part = %q, want %q", want.contentType, body, want.body)
+		}
+
+		if !strings.Contains(string(body), "RESETTOKEN") {
+			t.Errorf("%s part does not contain the token", want.contentType)
+		}
+	}
+
+	if _, err = parts.NextPart(); err != io.EOF {
+		t.Errorf("got more than two parts, want plain text and HTML only")
+	}
+}
@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP sends messages through an SMTP server, upgrading the connection with
+// STARTTLS when the server offers it. Authentication is skipped when no
+// username is set.
+type SMTP struct {
+	host     string
+	port     int
+	username string
+	password string
+	sender   string
+	timeout  time.Duration
+}
+
+func NewSMTP(host string, port int, username, password, sender string) *SMTP {
+	return &SMTP{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		sender:   sender,
+		timeout:  10 * time.Second,
+	}
+}
+
+func (s *SMTP) Send(msg *Message) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)), s.timeout)
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.username, s.password, s.host))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(s.sender); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if err = msg.writeTo(w, s.sender); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
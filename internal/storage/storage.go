@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage stores opaque blobs, such as receipt attachments, under
+// slash-separated keys like "expenses/42/5f1c...".
+type Storage interface {
+	// Put stores everything read from r under key, replacing any existing
+	// object. Nothing is left behind if reading from r fails.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Open returns the object stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting an object that
+	// does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE settlements DROP COLUMN IF EXISTS base_amount;
+ALTER TABLE settlements DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE settlements DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE expenses DROP COLUMN IF EXISTS base_amount;
+ALTER TABLE expenses DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE expenses DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,28 @@
+ALTER TABLE groups ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+ALTER TABLE expenses ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE expenses ADD COLUMN exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1 CHECK (exchange_rate > 0);
+ALTER TABLE expenses ADD COLUMN base_amount NUMERIC(10, 2);
+
+UPDATE expenses e SET currency = g.currency, base_amount = e.amount
+FROM groups g WHERE g.id = e.group_id;
+
+ALTER TABLE expenses ALTER COLUMN base_amount SET NOT NULL;
+
+ALTER TABLE settlements ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE settlements ADD COLUMN exchange_rate NUMERIC(18, 8) NOT NULL DEFAULT 1 CHECK (exchange_rate > 0);
+ALTER TABLE settlements ADD COLUMN base_amount NUMERIC(10, 2);
+
+UPDATE settlements s SET currency = g.currency, base_amount = s.amount
+FROM groups g WHERE g.id = s.group_id;
+
+ALTER TABLE settlements ALTER COLUMN base_amount SET NOT NULL;
+
+CREATE TABLE exchange_rates (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (group_id, currency)
+);
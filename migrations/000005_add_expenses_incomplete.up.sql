@@ -0,0 +1,8 @@
+ALTER TABLE expenses ADD COLUMN incomplete BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE expenses e SET incomplete = TRUE
+WHERE e.amount <> (
+    SELECT COALESCE(SUM(p.amount_owed), 0)
+    FROM expense_participants p
+    WHERE p.expense_id = e.id
+);
@@ -0,0 +1,34 @@
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entry_type TEXT NOT NULL,
+    expense_id INT REFERENCES expenses(id) ON DELETE CASCADE,
+    settlement_id INT REFERENCES settlements(id) ON DELETE CASCADE,
+    amount NUMERIC(12, 2) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    CHECK ((expense_id IS NULL) <> (settlement_id IS NULL))
+);
+
+CREATE INDEX idx_ledger_entries_member ON ledger_entries (group_id, user_id, occurred_at, id);
+CREATE INDEX idx_ledger_entries_expense_id ON ledger_entries (expense_id);
+CREATE INDEX idx_ledger_entries_settlement_id ON ledger_entries (settlement_id);
+
+INSERT INTO ledger_entries (group_id, user_id, entry_type, expense_id, settlement_id, amount, occurred_at)
+SELECT e.group_id, p.user_id, 'expense_share', e.id, NULL, ROUND(p.amount_owed * e.exchange_rate, 2), e.created_at
+FROM expense_participants p
+INNER JOIN expenses e ON e.id = p.expense_id
+UNION ALL
+SELECT e.group_id, e.paid_by, 'payer_credit', e.id, NULL, -SUM(ROUND(p.amount_owed * e.exchange_rate, 2)), e.created_at
+FROM expense_participants p
+INNER JOIN expenses e ON e.id = p.expense_id
+WHERE e.paid_by IS NOT NULL
+GROUP BY e.group_id, e.paid_by, e.id, e.created_at
+UNION ALL
+SELECT s.group_id, s.payer_id, 'settlement_paid', NULL, s.id, -s.base_amount, s.settled_at
+FROM settlements s
+WHERE s.payer_id IS NOT NULL
+UNION ALL
+SELECT s.group_id, s.payee_id, 'settlement_received', NULL, s.id, s.base_amount, s.settled_at
+FROM settlements s
+WHERE s.payee_id IS NOT NULL;
@@ -0,0 +1,27 @@
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    group_id INT REFERENCES groups(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Built-in categories have no group. COALESCE lets the index treat them as
+-- one more group, so names are unique among the built-ins too.
+CREATE UNIQUE INDEX categories_group_id_name_key ON categories (COALESCE(group_id, 0), lower(name));
+
+INSERT INTO categories (name) VALUES
+    ('General'),
+    ('Groceries'),
+    ('Restaurants'),
+    ('Rent'),
+    ('Utilities'),
+    ('Transport'),
+    ('Travel'),
+    ('Entertainment'),
+    ('Shopping'),
+    ('Health');
+
+ALTER TABLE expenses ADD COLUMN category_id INT REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_expenses_category_id ON expenses (category_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_settlements_group_id_settled_at;
+
+UPDATE ledger_entries le
+SET occurred_at = e.created_at
+FROM expenses e
+WHERE le.expense_id = e.id;
+
+ALTER TABLE expenses DROP COLUMN IF EXISTS incurred_on;
@@ -0,0 +1,11 @@
+ALTER TABLE expenses ADD COLUMN incurred_on DATE NOT NULL DEFAULT CURRENT_DATE;
+
+UPDATE expenses SET incurred_on = created_at::date WHERE created_at IS NOT NULL;
+
+UPDATE ledger_entries le
+SET occurred_at = e.incurred_on::timestamp
+FROM expenses e
+WHERE le.expense_id = e.id;
+
+CREATE INDEX idx_expenses_group_id_incurred_on ON expenses (group_id, incurred_on);
+CREATE INDEX idx_settlements_group_id_settled_at ON settlements (group_id, settled_at);
@@ -0,0 +1,3 @@
+ALTER TABLE expenses DROP COLUMN IF EXISTS recurring_expense_id;
+
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,25 @@
+CREATE TABLE recurring_expenses (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    paid_by INT REFERENCES users(id) ON DELETE SET NULL,
+    category_id INT REFERENCES categories(id) ON DELETE SET NULL,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    start_date DATE NOT NULL,
+    end_date DATE CHECK (end_date >= start_date),
+    split JSONB NOT NULL,
+    occurrences INT NOT NULL DEFAULT 0,
+    next_occurrence DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recurring_expenses_next_occurrence ON recurring_expenses (next_occurrence) WHERE next_occurrence IS NOT NULL;
+
+ALTER TABLE expenses ADD COLUMN recurring_expense_id INT REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+
+-- Guarantees that an occurrence is never materialized twice, even if the
+-- scheduler runs on several instances at once.
+CREATE UNIQUE INDEX expenses_recurring_expense_id_incurred_on_key ON expenses (recurring_expense_id, incurred_on);
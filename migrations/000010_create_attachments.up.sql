@@ -0,0 +1,12 @@
+CREATE TABLE attachments (
+    id SERIAL PRIMARY KEY,
+    expense_id INT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_attachments_expense_id ON attachments (expense_id);
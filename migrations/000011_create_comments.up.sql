@@ -0,0 +1,13 @@
+CREATE TABLE comments (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id INT REFERENCES expenses(id) ON DELETE CASCADE,
+    settlement_id INT REFERENCES settlements(id) ON DELETE CASCADE,
+    author_id INT REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((expense_id IS NULL) <> (settlement_id IS NULL))
+);
+
+CREATE INDEX idx_comments_expense_id ON comments (expense_id, created_at) WHERE expense_id IS NOT NULL;
+CREATE INDEX idx_comments_settlement_id ON comments (settlement_id, created_at) WHERE settlement_id IS NOT NULL;
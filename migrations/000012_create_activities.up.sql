@@ -0,0 +1,13 @@
+CREATE TABLE activities (
+    id BIGSERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    expense_id INT,
+    settlement_id INT,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_activities_group_id ON activities (group_id, id);
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity TEXT NOT NULL,
+    entity_id INT NOT NULL,
+    group_id INT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id);
+CREATE INDEX idx_audit_log_group_id ON audit_log (group_id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_settlements_trash;
+DROP INDEX IF EXISTS idx_expenses_trash;
+
+DELETE FROM settlements WHERE deleted_at IS NOT NULL;
+ALTER TABLE settlements DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE settlements DROP COLUMN IF EXISTS deleted_at;
+
+DELETE FROM expenses WHERE deleted_at IS NOT NULL;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+ALTER TABLE expenses ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE expenses ADD COLUMN deleted_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE settlements ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE settlements ADD COLUMN deleted_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_expenses_trash ON expenses (group_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_settlements_trash ON settlements (group_id, deleted_at) WHERE deleted_at IS NOT NULL;
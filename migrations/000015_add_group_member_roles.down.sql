@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_group_members_owner;
+
+ALTER TABLE group_members DROP CONSTRAINT IF EXISTS group_members_role_check;
+ALTER TABLE group_members DROP COLUMN IF EXISTS role;
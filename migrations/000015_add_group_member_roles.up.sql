@@ -0,0 +1,11 @@
+ALTER TABLE group_members ADD COLUMN role TEXT NOT NULL DEFAULT 'member';
+ALTER TABLE group_members ADD CONSTRAINT group_members_role_check CHECK (role IN ('owner', 'admin', 'member', 'viewer'));
+
+-- Whoever created a group becomes its owner.
+INSERT INTO group_members (group_id, user_id, role)
+SELECT id, created_by, 'owner'
+FROM groups
+WHERE created_by IS NOT NULL
+ON CONFLICT (group_id, user_id) DO UPDATE SET role = 'owner', is_active = TRUE, left_at = NULL;
+
+CREATE UNIQUE INDEX idx_group_members_owner ON group_members (group_id) WHERE role = 'owner';
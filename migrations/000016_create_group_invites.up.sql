@@ -0,0 +1,14 @@
+CREATE TABLE group_invites (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    hash bytea UNIQUE NOT NULL,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    email VARCHAR(150),
+    expiry timestamp(0) with time zone,
+    max_uses INT CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_group_invites_group_id ON group_invites (group_id);
+CREATE INDEX idx_group_invites_email ON group_invites (lower(email)) WHERE email IS NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE tokens ADD COLUMN id BIGSERIAL UNIQUE NOT NULL;
+ALTER TABLE tokens ADD COLUMN created_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+ALTER TABLE tokens ADD COLUMN last_used_at timestamp(0) with time zone;
+ALTER TABLE tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE tokens ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_tokens_user_id ON tokens (user_id, scope);
@@ -0,0 +1,8 @@
+DELETE FROM tokens WHERE scope = 'refresh';
+
+DROP INDEX IF EXISTS idx_tokens_family_id;
+
+ALTER TABLE tokens DROP COLUMN IF EXISTS used_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS family_id;
+
+DROP SEQUENCE IF EXISTS token_families;
@@ -0,0 +1,14 @@
+-- Every login starts a family of tokens: the access token and the refresh
+-- token issued with it, and every pair they are later rotated into.
+CREATE SEQUENCE token_families;
+
+ALTER TABLE tokens ADD COLUMN family_id BIGINT;
+ALTER TABLE tokens ADD COLUMN used_at timestamp(0) with time zone;
+
+UPDATE tokens SET family_id = id;
+SELECT setval('token_families', COALESCE(MAX(id), 0) + 1, false) FROM tokens;
+
+ALTER TABLE tokens ALTER COLUMN family_id SET DEFAULT nextval('token_families');
+ALTER TABLE tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX idx_tokens_family_id ON tokens (family_id);